@@ -21,7 +21,9 @@ import (
 	"flag"
 	"github.com/SENERGY-Platform/process-scheduler/pkg"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/logging"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -35,21 +37,30 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = logging.Setup(config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	wg, err := pkg.Start(ctx, config)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("unable to start", "error", err)
+		os.Exit(1)
 	}
 
 	go func() {
 		shutdown := make(chan os.Signal, 1)
 		signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
 		sig := <-shutdown
-		log.Println("received shutdown signal", sig)
+		slog.Info("received shutdown signal; stop gracefully", "signal", sig.String())
 		cancel()
+		sig = <-shutdown
+		slog.Warn("received second shutdown signal; exit immediately", "signal", sig.String())
+		os.Exit(1)
 	}()
 
 	wg.Wait()
+	slog.Info("shutdown complete")
 }
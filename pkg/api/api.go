@@ -20,46 +20,84 @@ import (
 	"context"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
 	"github.com/julienschmidt/httprouter"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
 
 var endpoints = []func(router *httprouter.Router, config configuration.Config, jwt util.Jwt, control *scheduler.Scheduler){}
 
-//starts http server; if wg is not nil it will be set as done when the server is stopped
-func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, ctrl *scheduler.Scheduler, jwt util.Jwt) (err error) {
-	log.Println("start api on " + config.ApiPort)
-	router := Router(config, ctrl, jwt)
+// starts http server; if wg is not nil it will be set as done when the server is stopped
+func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, ctrl *scheduler.Scheduler, jwt util.Jwt, m *metrics.Metrics, t *tracing.Tracing) (err error) {
+	slog.Info("start api", "port", config.ApiPort)
+	router := Router(config, ctrl, jwt, m, t)
 	server := &http.Server{Addr: ":" + config.ApiPort, Handler: router, WriteTimeout: 10 * time.Second, ReadTimeout: 2 * time.Second, ReadHeaderTimeout: 2 * time.Second}
 	wg.Add(1)
 	go func() {
-		log.Println("Listening on ", server.Addr)
+		slog.Info("listening", "address", server.Addr)
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			log.Println("ERROR: api server error", err)
-			log.Fatal(err)
+			slog.Error("api server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 	go func() {
 		<-ctx.Done()
-		log.Println("DEBUG: api shutdown", server.Shutdown(context.Background()))
+		slog.Debug("api shutdown", "result", server.Shutdown(context.Background()))
 		wg.Done()
 	}()
 	return nil
 }
 
-func Router(config configuration.Config, ctrl *scheduler.Scheduler, jwt util.Jwt) http.Handler {
+// Router creates the api handler; m and t may be nil to disable metrics and tracing
+func Router(config configuration.Config, ctrl *scheduler.Scheduler, jwt util.Jwt, m *metrics.Metrics, t *tracing.Tracing) http.Handler {
 	router := httprouter.New()
 	for _, e := range endpoints {
-		log.Println("add endpoints: " + runtime.FuncForPC(reflect.ValueOf(e).Pointer()).Name())
+		slog.Debug("add endpoints", "endpoints", runtime.FuncForPC(reflect.ValueOf(e).Pointer()).Name())
 		e(router, config, jwt, ctrl)
 	}
-	log.Println("add logging and cors")
-	corsHandler := util.NewCors(router)
-	return util.NewLogger(corsHandler)
+	var observer util.RequestObserver
+	if m != nil {
+		slog.Debug("add metrics endpoint")
+		router.Handler(http.MethodGet, "/metrics", m.Handler())
+		observer = func(request *http.Request, statusCode int, size int, duration time.Duration) {
+			m.ObserveRequest(request.Method, getRoute(router, request), statusCode, duration, size)
+		}
+	}
+	var handler http.Handler = router
+	if t != nil {
+		slog.Debug("add tracing")
+		handler = util.NewTracing(router, t, func(request *http.Request) string {
+			return getRoute(router, request)
+		})
+	}
+	slog.Debug("add logging and cors")
+	corsHandler := util.NewCors(handler)
+	return util.NewLogger(corsHandler, jwt, observer)
+}
+
+// getRoute returns the registered route pattern of the request (e.g. /schedules/:id) to keep the label cardinality low
+func getRoute(router *httprouter.Router, request *http.Request) string {
+	handle, params, _ := router.Lookup(request.Method, request.URL.Path)
+	if handle == nil {
+		return "unknown"
+	}
+	segments := strings.Split(request.URL.Path, "/")
+	for _, param := range params {
+		for i, segment := range segments {
+			if segment == param.Value {
+				segments[i] = ":" + param.Key
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
 }
@@ -19,6 +19,14 @@ package api
 import (
 	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"github.com/golang-jwt/jwt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -29,5 +37,132 @@ func TestRoutes(t *testing.T) {
 		}
 	}()
 	conf := &configuration.ConfigStruct{}
-	Router(conf, nil, util.NewJwt(conf))
+	Router(conf, nil, util.NewJwt(conf), nil, nil)
+}
+
+func TestListLimit(t *testing.T) {
+	cases := map[string]int64{
+		"":            model.DEFAULT_LIST_LIMIT,
+		"?limit=1":    1,
+		"?limit=1000": model.MAX_LIST_LIMIT,
+		"?limit=0":    -1,
+		"?limit=1001": -1,
+		"?limit=-1":   -1,
+	}
+	for query, expected := range cases {
+		options, err := getListOptions(httptest.NewRequest(http.MethodGet, "/schedules"+query, nil))
+		if expected < 0 {
+			if err == nil {
+				t.Error("expected error", query)
+			}
+			continue
+		}
+		if err != nil || options.Limit != expected {
+			t.Error(query, err, options.Limit)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	conf := &configuration.ConfigStruct{}
+	router := Router(conf, nil, util.NewJwt(conf), metrics.New(), nil)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/schedules/some-id", nil))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Error(recorder.Code)
+		return
+	}
+	body, _ := io.ReadAll(recorder.Body)
+	for _, expected := range []string{
+		`process_scheduler_http_requests_total{method="GET",route="/schedules/:id",status_code="401"} 1`,
+		"process_scheduler_jobs",
+		"process_scheduler_scheduling_lag_seconds",
+		"process_scheduler_execution_duration_seconds",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
+
+func TestRequestId(t *testing.T) {
+	conf := &configuration.ConfigStruct{}
+	router := Router(conf, nil, util.NewJwt(conf), nil, nil)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/schedules/some-id", nil))
+	if recorder.Header().Get(util.RequestIdHeader) == "" {
+		t.Error("missing generated request id")
+	}
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/schedules/some-id", nil)
+	request.Header.Set(util.RequestIdHeader, "foo")
+	router.ServeHTTP(recorder, request)
+	if recorder.Header().Get(util.RequestIdHeader) != "foo" {
+		t.Error("unexpected request id", recorder.Header().Get(util.RequestIdHeader))
+	}
+}
+
+func TestAdminRoutesRequireAdminRole(t *testing.T) {
+	conf := &configuration.ConfigStruct{}
+	router := Router(conf, nil, util.NewJwt(conf), nil, nil)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/blackouts", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Error("unexpected status without token", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/admin/blackouts", nil)
+	err := processapi.SetAuthToken(request, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusForbidden {
+		t.Error("unexpected status without admin role", recorder.Code)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":          "admin",
+		"realm_access": map[string]interface{}{"roles": []string{util.ADMIN_ROLE}},
+	}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request = httptest.NewRequest(http.MethodGet, "/admin/blackouts", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	if !util.NewJwt(conf).IsAdmin(request) {
+		t.Error("admin role not detected")
+	}
+	if roles := util.NewJwt(conf).GetRoles(request); len(roles) != 1 || roles[0] != util.ADMIN_ROLE {
+		t.Error("unexpected roles", roles)
+	}
+}
+
+func TestQuotaAdminRoutesRequireAdminRole(t *testing.T) {
+	conf := &configuration.ConfigStruct{}
+	router := Router(conf, nil, util.NewJwt(conf), nil, nil)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/quotas"},
+		{http.MethodGet, "/admin/quotas/user"},
+		{http.MethodPut, "/admin/quotas/user"},
+		{http.MethodDelete, "/admin/quotas/user"},
+	} {
+		request := httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+		err := processapi.SetAuthToken(request, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusForbidden {
+			t.Error("unexpected status without admin role", route, recorder.Code)
+		}
+	}
 }
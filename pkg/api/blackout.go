@@ -0,0 +1,128 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/julienschmidt/httprouter"
+	"log/slog"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, BlackoutEndpoints)
+}
+
+// BlackoutEndpoints registers the admin api for global blackout windows; all routes require the admin role
+func BlackoutEndpoints(router *httprouter.Router, config configuration.Config, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+	router.GET("/admin/blackouts", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		_, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		result, err, code := ctrl.ListBlackoutWindows(request.Context())
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.POST("/admin/blackouts", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		window := model.BlackoutWindow{}
+		err := json.NewDecoder(request.Body).Decode(&window)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.AddBlackoutWindow(request.Context(), window, user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.PUT("/admin/blackouts/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		window := model.BlackoutWindow{}
+		err := json.NewDecoder(request.Body).Decode(&window)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if window.Id == "" {
+			window.Id = params.ByName("id")
+		}
+		if window.Id != params.ByName("id") {
+			http.Error(writer, model.ErrorIdMissmatch.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.UpdateBlackoutWindow(request.Context(), window, user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.DELETE("/admin/blackouts/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		err, code := ctrl.RemoveBlackoutWindow(request.Context(), params.ByName("id"), user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	})
+}
+
+// parseAdminRequest writes 401 or 403 and returns false if the request is not authorized as admin
+func parseAdminRequest(writer http.ResponseWriter, request *http.Request, jwt util.Jwt) (user string, ok bool) {
+	user, err := jwt.ParseRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return user, false
+	}
+	if !jwt.IsAdmin(request) {
+		http.Error(writer, util.ErrorNotAdmin.Error(), http.StatusForbidden)
+		return user, false
+	}
+	return user, true
+}
+
+func writeJson(writer http.ResponseWriter, request *http.Request, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err := json.NewEncoder(writer).Encode(value)
+	if err != nil {
+		slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+func init() {
+	endpoints = append(endpoints, CalendarEndpoints)
+}
+
+// CalendarEndpoints registers the api for the calendars of a user;
+// calendars are created or replaced with PUT, either as JSON or as iCalendar document (Content-Type text/calendar)
+func CalendarEndpoints(router *httprouter.Router, config configuration.Config, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+	router.GET("/calendars", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err, code := ctrl.ListCalendars(request.Context(), user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.GET("/calendars/:name", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err, code := ctrl.GetCalendar(request.Context(), params.ByName("name"), user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.PUT("/calendars/:name", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		calendar := model.Calendar{}
+		contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+		switch contentType {
+		case "text/calendar":
+			body, err := io.ReadAll(request.Body)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			calendar, err = model.ParseICalendar(body, time.Now())
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case "", "application/json":
+			err = json.NewDecoder(request.Body).Decode(&calendar)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(writer, "expect Content-Type application/json or text/calendar", http.StatusUnsupportedMediaType)
+			return
+		}
+		if calendar.Name == "" {
+			calendar.Name = params.ByName("name")
+		}
+		if calendar.Name != params.ByName("name") {
+			http.Error(writer, model.ErrorIdMissmatch.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.SetCalendar(request.Context(), calendar, user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.DELETE("/calendars/:name", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		err, code := ctrl.RemoveCalendar(request.Context(), params.ByName("name"), user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	})
+}
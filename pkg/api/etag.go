@@ -0,0 +1,46 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func setETag(writer http.ResponseWriter, version int64) {
+	writer.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// getIfMatchVersion returns the schedule version expected by the If-Match header;
+// nil if the header is missing or "*"
+func getIfMatchVersion(request *http.Request) (*int64, error) {
+	ifMatch := strings.TrimSpace(request.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return nil, nil
+	}
+	unquoted, err := strconv.Unquote(ifMatch)
+	if err != nil {
+		return nil, model.ErrorInvalidVersion
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, model.ErrorInvalidVersion
+	}
+	return &version, nil
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	endpoints = append(endpoints, ExportEndpoints)
+}
+
+// ExportEndpoints registers POST /schedules/import;
+// GET /schedules/export is dispatched by the GET /schedules/:id handler because httprouter does not allow static routes next to the :id wildcard;
+// imports reject "export" as schedule id (model.ReservedIds), so that no schedule is hidden behind this route
+func ExportEndpoints(router *httprouter.Router, config configuration.Config, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+	router.POST("/schedules/import", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		importRequest, err := parseImportRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.Import(request.Context(), importRequest, user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+			return
+		}
+	})
+}
+
+func exportSchedules(writer http.ResponseWriter, request *http.Request, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+	user, err := jwt.ParseRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	result, err, code := ctrl.Export(request.Context(), user)
+	if err != nil {
+		http.Error(writer, err.Error(), code)
+		return
+	}
+	if !wantsYaml(request) {
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+		}
+		return
+	}
+	temp, err := json.Marshal(result)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var generic interface{}
+	err = json.Unmarshal(temp, &generic)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	err = yaml.NewEncoder(writer).Encode(generic)
+	if err != nil {
+		slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+	}
+}
+
+func wantsYaml(request *http.Request) bool {
+	if format := request.URL.Query().Get("format"); format != "" {
+		return format == "yaml" || format == "yml"
+	}
+	return strings.Contains(request.Header.Get("Accept"), "yaml")
+}
+
+// parseImportRequest accepts a json or yaml body that is either a model.ImportRequest
+// or a plain model.ExportDocument; for plain documents the strategy and dry_run options are read from the query
+func parseImportRequest(request *http.Request) (result model.ImportRequest, err error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return result, err
+	}
+	contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if strings.Contains(contentType, "yaml") {
+		var generic interface{}
+		err = yaml.Unmarshal(body, &generic)
+		if err != nil {
+			return result, err
+		}
+		body, err = json.Marshal(generic)
+		if err != nil {
+			return result, err
+		}
+	}
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		return result, err
+	}
+	if _, ok := fields["document"]; ok {
+		err = json.Unmarshal(body, &result)
+		return result, err
+	}
+	err = json.Unmarshal(body, &result.Document)
+	if err != nil {
+		return result, err
+	}
+	query := request.URL.Query()
+	result.Strategy = query.Get("strategy")
+	if dryRun := query.Get("dry_run"); dryRun != "" {
+		result.DryRun, err = strconv.ParseBool(dryRun)
+	}
+	return result, err
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/julienschmidt/httprouter"
+	"log/slog"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, HealthEndpoints)
+}
+
+// HealthEndpoints provides the kubernetes probes; they need no authentication
+func HealthEndpoints(router *httprouter.Router, config configuration.Config, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+
+	router.GET("/health/live", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		writeHealth(writer, request, model.HealthReport{Status: model.HealthStatusUp, Components: map[string]model.ComponentHealth{}})
+	})
+
+	router.GET("/health/ready", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		writeHealth(writer, request, ctrl.Ready(request.Context()))
+	})
+}
+
+func writeHealth(writer http.ResponseWriter, request *http.Request, report model.HealthReport) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	if report.IsUp() {
+		writer.WriteHeader(http.StatusOK)
+	} else {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(writer).Encode(report)
+	if err != nil {
+		slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/julienschmidt/httprouter"
+	"log/slog"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, PauseEndpoints)
+}
+
+// PauseEndpoints registers POST /schedules/pause and POST /schedules/resume (the request body is a model.PauseSelector)
+// and GET /pauses, which lists the stored pause records of the user
+func PauseEndpoints(router *httprouter.Router, config configuration.Config, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+	router.GET("/pauses", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err, code := ctrl.ListPauses(request.Context(), user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+	router.POST("/schedules/pause", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		handlePauseRequest(writer, request, jwt, ctrl.Pause)
+	})
+	router.POST("/schedules/resume", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		handlePauseRequest(writer, request, jwt, ctrl.Resume)
+	})
+}
+
+func handlePauseRequest(writer http.ResponseWriter, request *http.Request, jwt util.Jwt, handler func(ctx context.Context, selector model.PauseSelector, user string) (model.PauseResponse, error, int)) {
+	user, err := jwt.ParseRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	selector := model.PauseSelector{}
+	err = json.NewDecoder(request.Body).Decode(&selector)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err, code := handler(request.Context(), selector, user)
+	if err != nil {
+		http.Error(writer, err.Error(), code)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(writer).Encode(result)
+	if err != nil {
+		slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+		return
+	}
+}
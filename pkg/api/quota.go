@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, QuotaEndpoints)
+}
+
+// QuotaEndpoints registers the quota of the requesting user and the admin api for per user quota overrides
+func QuotaEndpoints(router *httprouter.Router, config configuration.Config, jwt util.Jwt, ctrl *scheduler.Scheduler) {
+	router.GET("/quota", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err, code := ctrl.GetQuota(request.Context(), user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.GET("/admin/quotas", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		_, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		result, err, code := ctrl.ListQuotaOverrides(request.Context())
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.GET("/admin/quotas/:user", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		_, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		result, err, code := ctrl.GetQuota(request.Context(), params.ByName("user"))
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.PUT("/admin/quotas/:user", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		admin, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		override := model.QuotaOverride{}
+		err := json.NewDecoder(request.Body).Decode(&override)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if override.User == "" {
+			override.User = params.ByName("user")
+		}
+		if override.User != params.ByName("user") {
+			http.Error(writer, model.ErrorUserMissmatch.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.SetQuotaOverride(request.Context(), override, admin)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writeJson(writer, request, result)
+	})
+
+	router.DELETE("/admin/quotas/:user", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		_, ok := parseAdminRequest(writer, request, jwt)
+		if !ok {
+			return
+		}
+		err, code := ctrl.RemoveQuotaOverride(request.Context(), params.ByName("user"))
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	})
+}
@@ -18,14 +18,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
 	"github.com/julienschmidt/httprouter"
-	"log"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
-	"runtime/debug"
+	"strconv"
+	"strings"
 )
 
 func init() {
@@ -51,16 +56,42 @@ func SchedulerEndpoints(router *httprouter.Router, config configuration.Config,
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
-		result, err, code := ctrl.Add(entry, user)
+		result, err, code := ctrl.Add(request.Context(), entry, user)
 		if err != nil {
 			http.Error(writer, err.Error(), code)
 			return
 		}
+		setETag(writer, result.Version)
 		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 		err = json.NewEncoder(writer).Encode(result)
 		if err != nil {
-			log.Println("ERROR:", err)
-			debug.PrintStack()
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+			return
+		}
+	})
+
+	router.POST("/schedules/bulk", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		bulk := model.BulkRequest{}
+		err = json.NewDecoder(request.Body).Decode(&bulk)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.Bulk(request.Context(), bulk, user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.WriteHeader(code)
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
 			return
 		}
 	})
@@ -83,16 +114,57 @@ func SchedulerEndpoints(router *httprouter.Router, config configuration.Config,
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
-		result, err, code := ctrl.Update(entry, user)
+		expectedVersion, err := getIfMatchVersion(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.Update(request.Context(), entry, user, expectedVersion)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		setETag(writer, result.Version)
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+			return
+		}
+	})
+
+	router.PATCH("/schedules/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+		if contentType != "" && contentType != "application/merge-patch+json" && contentType != "application/json" {
+			http.Error(writer, "expect Content-Type application/merge-patch+json", http.StatusUnsupportedMediaType)
+			return
+		}
+		patch, err := io.ReadAll(request.Body)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		expectedVersion, err := getIfMatchVersion(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.Patch(request.Context(), id, user, patch, expectedVersion)
 		if err != nil {
 			http.Error(writer, err.Error(), code)
 			return
 		}
+		setETag(writer, result.Version)
 		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 		err = json.NewEncoder(writer).Encode(result)
 		if err != nil {
-			log.Println("ERROR:", err)
-			debug.PrintStack()
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
 			return
 		}
 	})
@@ -103,8 +175,62 @@ func SchedulerEndpoints(router *httprouter.Router, config configuration.Config,
 			http.Error(writer, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		createdBy := request.URL.Query().Get("created_by")
-		result, err, code := ctrl.List(user, &createdBy)
+		options, err := getListOptions(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, total, err, code := ctrl.List(request.Context(), user, options)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+			return
+		}
+	})
+
+	router.GET("/schedules/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if id == "export" {
+			exportSchedules(writer, request, jwt, ctrl)
+			return
+		}
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err, code := ctrl.Get(request.Context(), id, user)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		setETag(writer, result.Version)
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
+			return
+		}
+	})
+
+	router.GET("/schedules/:id/next", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		user, err := jwt.ParseRequest(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		count, includeSkipped, err := getNextRunsOptions(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := ctrl.NextRuns(request.Context(), params.ByName("id"), user, count, includeSkipped)
 		if err != nil {
 			http.Error(writer, err.Error(), code)
 			return
@@ -112,8 +238,7 @@ func SchedulerEndpoints(router *httprouter.Router, config configuration.Config,
 		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 		err = json.NewEncoder(writer).Encode(result)
 		if err != nil {
-			log.Println("ERROR:", err)
-			debug.PrintStack()
+			slog.ErrorContext(request.Context(), "unable to write response", "error", err)
 			return
 		}
 	})
@@ -125,7 +250,7 @@ func SchedulerEndpoints(router *httprouter.Router, config configuration.Config,
 			http.Error(writer, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		err, code := ctrl.Delete(id, user)
+		err, code := ctrl.Delete(request.Context(), id, user)
 		if err != nil {
 			http.Error(writer, err.Error(), code)
 			return
@@ -133,3 +258,70 @@ func SchedulerEndpoints(router *httprouter.Router, config configuration.Config,
 		writer.WriteHeader(http.StatusOK)
 	})
 }
+
+// getNextRunsOptions parses the count (default scheduler.DEFAULT_PREVIEW_COUNT) and include_skipped query parameters
+func getNextRunsOptions(request *http.Request) (count int, includeSkipped bool, err error) {
+	query := request.URL.Query()
+	count = scheduler.DEFAULT_PREVIEW_COUNT
+	if value := query.Get("count"); value != "" {
+		count, err = strconv.Atoi(value)
+		if err != nil || count < 1 || count > scheduler.MAX_PREVIEW_COUNT {
+			return count, includeSkipped, fmt.Errorf("invalid count parameter; expect 1 to %v", scheduler.MAX_PREVIEW_COUNT)
+		}
+	}
+	if query.Has("include_skipped") {
+		includeSkipped, err = strconv.ParseBool(query.Get("include_skipped"))
+		if err != nil {
+			return count, includeSkipped, fmt.Errorf("invalid include_skipped parameter: %w", err)
+		}
+	}
+	return count, includeSkipped, nil
+}
+
+// getListOptions parses the filter, sort and paging query parameters; limit defaults to model.DEFAULT_LIST_LIMIT
+func getListOptions(request *http.Request) (options model.ListOptions, err error) {
+	query := request.URL.Query()
+	if query.Has("created_by") {
+		createdBy := query.Get("created_by")
+		options.CreatedBy = &createdBy
+	}
+	if query.Has("process_deployment_id") {
+		deploymentId := query.Get("process_deployment_id")
+		options.ProcessDeploymentId = &deploymentId
+	}
+	if query.Has("disabled") {
+		disabled, err := strconv.ParseBool(query.Get("disabled"))
+		if err != nil {
+			return options, fmt.Errorf("invalid disabled parameter: %w", err)
+		}
+		options.Disabled = &disabled
+	}
+	if query.Has("paused") {
+		paused, err := strconv.ParseBool(query.Get("paused"))
+		if err != nil {
+			return options, fmt.Errorf("invalid paused parameter: %w", err)
+		}
+		options.Paused = &paused
+	}
+	options.AliasSearch = query.Get("search")
+	if tags := query.Get("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			options.Tags = append(options.Tags, strings.TrimSpace(tag))
+		}
+	}
+	options.Limit = model.DEFAULT_LIST_LIMIT
+	if limit := query.Get("limit"); limit != "" {
+		options.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || options.Limit < 1 || options.Limit > model.MAX_LIST_LIMIT {
+			return options, fmt.Errorf("invalid limit parameter; expect 1 to %v", model.MAX_LIST_LIMIT)
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		options.Offset, err = strconv.ParseInt(offset, 10, 64)
+		if err != nil || options.Offset < 0 {
+			return options, errors.New("invalid offset parameter")
+		}
+	}
+	err = options.ParseSort(query.Get("sort"))
+	return options, err
+}
@@ -26,6 +26,8 @@ import (
 
 type Jwt interface {
 	ParseRequest(request *http.Request) (user string, err error)
+	IsAdmin(request *http.Request) bool
+	GetRoles(request *http.Request) []string
 }
 
 type JwtImpl struct {
@@ -36,6 +38,10 @@ func NewJwt(config configuration.Config) Jwt {
 	return JwtImpl{config: config}
 }
 
+const ADMIN_ROLE = "admin"
+
+var ErrorNotAdmin = errors.New("access denied: admin role required")
+
 const PEM_BEGIN = "-----BEGIN PUBLIC KEY-----"
 const PEM_END = "-----END PUBLIC KEY-----"
 
@@ -64,3 +70,35 @@ func (this JwtImpl) ParseRequest(request *http.Request) (user string, err error)
 	}
 	return this.Parse(strings.Join(authParts[1:], " "))
 }
+
+// IsAdmin checks if the token of the request contains the admin realm role
+func (this JwtImpl) IsAdmin(request *http.Request) bool {
+	for _, role := range this.GetRoles(request) {
+		if role == ADMIN_ROLE {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRoles returns the realm roles of the token of the request
+func (this JwtImpl) GetRoles(request *http.Request) (result []string) {
+	authParts := strings.Split(request.Header.Get("Authorization"), " ")
+	if len(authParts) != 2 {
+		return nil
+	}
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{}
+	_, _, err := parser.ParseUnverified(authParts[1], &claims)
+	if err != nil {
+		return nil
+	}
+	realmAccess, _ := claims["realm_access"].(map[string]interface{})
+	roles, _ := realmAccess["roles"].([]interface{})
+	for _, role := range roles {
+		if name, ok := role.(string); ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -17,27 +17,101 @@
 package util
 
 import (
-	"log"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/logging"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/google/uuid"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
-func NewLogger(handler http.Handler) *LoggerMiddleWare {
-	return &LoggerMiddleWare{handler: handler}
+const RequestIdHeader = "X-Request-Id"
+
+// RequestObserver is called after each handled request
+type RequestObserver func(request *http.Request, statusCode int, size int, duration time.Duration)
+
+// NewLogger wraps handler; jwt is used to add the user to the log context and the roles to the request context (see model.GetRoles).
+// jwt may be nil, as may observer
+func NewLogger(handler http.Handler, jwt Jwt, observer RequestObserver) *LoggerMiddleWare {
+	return &LoggerMiddleWare{handler: handler, jwt: jwt, observer: observer}
 }
 
+// LoggerMiddleWare assigns a request id (taken from the X-Request-Id header if present) to each request,
+// stores it in the request context for all following log records and logs the handled request
 type LoggerMiddleWare struct {
-	handler http.Handler
+	handler  http.Handler
+	jwt      Jwt
+	observer RequestObserver
 }
 
 func (this *LoggerMiddleWare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	this.log(r)
+	start := time.Now()
+	requestId := r.Header.Get(RequestIdHeader)
+	if requestId == "" {
+		requestId = uuid.New().String()
+	}
+	w.Header().Set(RequestIdHeader, requestId)
+	ctx := logging.WithRequestId(r.Context(), requestId)
+	if this.jwt != nil {
+		if user, err := this.jwt.ParseRequest(r); err == nil {
+			ctx = logging.WithUser(ctx, user)
+		}
+		ctx = model.WithRoles(ctx, this.jwt.GetRoles(r))
+	}
+	r = r.WithContext(ctx)
+
+	recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
 	if this.handler != nil {
-		this.handler.ServeHTTP(w, r)
+		this.handler.ServeHTTP(recorder, r)
 	} else {
-		http.Error(w, "Forbidden", 403)
+		http.Error(recorder, "Forbidden", 403)
+	}
+	duration := time.Since(start)
+	if this.observer != nil {
+		this.observer(r, recorder.statusCode, recorder.size, duration)
+	}
+	this.log(r, recorder.statusCode, recorder.size, duration)
+}
+
+func (this *LoggerMiddleWare) log(request *http.Request, statusCode int, size int, duration time.Duration) {
+	level := slog.LevelInfo
+	if statusCode >= http.StatusInternalServerError {
+		level = slog.LevelError
 	}
+	slog.LogAttrs(request.Context(), level, "http request",
+		slog.String("method", request.Method),
+		slog.String("path", request.URL.Path),
+		slog.String("query", request.URL.RawQuery),
+		slog.Int("status", statusCode),
+		slog.Int64("duration_ms", duration.Milliseconds()),
+		slog.Int("size", size),
+	)
 }
 
-func (this *LoggerMiddleWare) log(request *http.Request) {
-	log.Printf("[%v] %v \n", request.Method, request.URL)
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	size        int
+	wroteHeader bool
+}
+
+func (this *responseRecorder) WriteHeader(statusCode int) {
+	if !this.wroteHeader {
+		this.statusCode = statusCode
+		this.wroteHeader = true
+	}
+	this.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (this *responseRecorder) Write(b []byte) (int, error) {
+	this.wroteHeader = true
+	n, err := this.ResponseWriter.Write(b)
+	this.size += n
+	return n, err
+}
+
+func (this *responseRecorder) Flush() {
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// NewTracing wraps handler in a server span per request; an incoming W3C trace context is used as parent.
+// route names the span (e.g. "GET /schedules/:id") to keep span names independent of ids.
+func NewTracing(handler http.Handler, t *tracing.Tracing, route func(request *http.Request) string) *TracingMiddleWare {
+	return &TracingMiddleWare{handler: handler, tracing: t, route: route}
+}
+
+type TracingMiddleWare struct {
+	handler http.Handler
+	tracing *tracing.Tracing
+	route   func(request *http.Request) string
+}
+
+func (this *TracingMiddleWare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := this.route(r)
+	ctx := tracing.Propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := this.tracing.Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("http.request.method", r.Method),
+		attribute.String("http.route", route),
+		attribute.String("url.path", r.URL.Path),
+	))
+	defer span.End()
+	recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	this.handler.ServeHTTP(recorder, r.WithContext(ctx))
+	span.SetAttributes(attribute.Int("http.response.status_code", recorder.statusCode))
+	if recorder.statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(recorder.statusCode))
+	}
+}
@@ -28,11 +28,41 @@ import (
 )
 
 type ConfigStruct struct {
-	ApiPort         string `json:"api_port"`
-	MongoUrl        string `json:"mongo_url"`
-	MongoTable      string `json:"mongo_table"`
-	MongoCollection string `json:"mongo_collection"`
-	ProcessEndpoint string `json:"process_endpoint"`
+	ApiPort                   string `json:"api_port"`
+	MongoUrl                  string `json:"mongo_url"`
+	MongoTable                string `json:"mongo_table"`
+	MongoCollection           string `json:"mongo_collection"`
+	MongoMetadataCollection   string `json:"mongo_metadata_collection"`
+	MongoExecutionCollection  string `json:"mongo_execution_collection"`
+	MongoBlackoutCollection   string `json:"mongo_blackout_collection"`
+	MongoCalendarCollection   string `json:"mongo_calendar_collection"`
+	MongoQuotaCollection      string `json:"mongo_quota_collection"`
+	MongoQuotaLockCollection  string `json:"mongo_quota_lock_collection"`
+	MongoPauseCollection      string `json:"mongo_pause_collection"`
+	ExecutionHistoryRetention string `json:"execution_history_retention"`
+	ProcessEndpoint           string `json:"process_endpoint"`
+	TracingExporter           string `json:"tracing_exporter"` //none, stdout or otlp
+	TracingEndpoint           string `json:"tracing_endpoint"` //otlp http endpoint (host:port); if empty, the OTEL_EXPORTER_OTLP_* env vars are used
+	LogLevel                  string `json:"log_level"`        //debug, info, warn or error
+	LogFormat                 string `json:"log_format"`       //json or text
+	ShutdownGracePeriod       string `json:"shutdown_grace_period"`
+	ReconcileInterval         string `json:"reconcile_interval"` //interval of the comparison of cron registry and database; 0 disables it
+	SpreadWindow              string `json:"spread_window"`      //cron firings are delayed by an offset in this window derived from the schedule id; empty or 0 disables it
+
+	ExecutionRateLimit      float64           `json:"execution_rate_limit"`      //max process starts per second of all users; 0 disables the limit
+	ExecutionRateBurst      int64             `json:"execution_rate_burst"`      //process starts above the rate limit that are allowed at once
+	UserExecutionRateLimit  float64           `json:"user_execution_rate_limit"` //max process starts per second of each user; 0 disables the limit
+	UserExecutionRateBurst  int64             `json:"user_execution_rate_burst"`
+	ExecutionOverflowPolicy string            `json:"execution_overflow_policy"` //queue (default), drop or defer
+	MinExecutionInterval    map[string]string `json:"min_execution_interval"`    //role -> min duration between two firings of an entry; "default" applies to users without a listed role
+
+	WorkerPoolSize  int64             `json:"worker_pool_size"`  //max concurrent process starts; 0 disables the pool
+	WorkerQueueSize int64             `json:"worker_queue_size"` //max firings waiting for a worker; 0 is unlimited
+	MaxPriority     map[string]string `json:"max_priority"`      //role -> highest priority of entries (high, normal or low); "default" applies to users without a listed role; normal if not set
+
+	MaxSchedulesPerUser        int64 `json:"max_schedules_per_user"` //default quotas; 0 is unlimited; admins may override them per user; enforced across instances with a per user lock in mongo_quota_lock_collection
+	MaxEnabledSchedulesPerUser int64 `json:"max_enabled_schedules_per_user"`
+	MaxSchedulesPerDeployment  int64 `json:"max_schedules_per_deployment"` //per user and process deployment
 }
 
 type Config = *ConfigStruct
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const FormatJson = "json"
+const FormatText = "text"
+
+// New creates a logger according to config.LogLevel (debug, info, warn, error; default info)
+// and config.LogFormat (json or text; default json). Correlation ids stored in the context
+// (see WithRequestId, WithUser, WithScheduleId, WithExecutionId) and the current trace id are added to each record.
+func New(config configuration.Config, out io.Writer) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if config.LogLevel != "" {
+		err := level.UnmarshalText([]byte(config.LogLevel))
+		if err != nil {
+			return nil, fmt.Errorf("invalid log_level %q: %w", config.LogLevel, err)
+		}
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(config.LogFormat) {
+	case "", FormatJson:
+		handler = slog.NewJSONHandler(out, options)
+	case FormatText:
+		handler = slog.NewTextHandler(out, options)
+	default:
+		return nil, fmt.Errorf("unknown log_format %q; expect json or text", config.LogFormat)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// Setup creates a logger writing to stdout and sets it as default for slog and the standard log package
+func Setup(config configuration.Config) error {
+	logger, err := New(config, os.Stdout)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+type contextKey string
+
+const (
+	requestIdKey   contextKey = "request_id"
+	userKey        contextKey = "user"
+	scheduleIdKey  contextKey = "schedule_id"
+	executionIdKey contextKey = "execution_id"
+)
+
+var contextKeys = []contextKey{requestIdKey, userKey, scheduleIdKey, executionIdKey}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return withValue(ctx, requestIdKey, id)
+}
+
+func WithUser(ctx context.Context, user string) context.Context {
+	return withValue(ctx, userKey, user)
+}
+
+func WithScheduleId(ctx context.Context, id string) context.Context {
+	return withValue(ctx, scheduleIdKey, id)
+}
+
+func WithExecutionId(ctx context.Context, id string) context.Context {
+	return withValue(ctx, executionIdKey, id)
+}
+
+func RequestId(ctx context.Context) string {
+	result, _ := ctx.Value(requestIdKey).(string)
+	return result
+}
+
+func withValue(ctx context.Context, key contextKey, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, key, value)
+}
+
+// contextHandler adds the correlation ids of the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (this *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		for _, key := range contextKeys {
+			if value, ok := ctx.Value(key).(string); ok {
+				record.AddAttrs(slog.String(string(key), value))
+			}
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+		}
+	}
+	return this.Handler.Handle(ctx, record)
+}
+
+func (this *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: this.Handler.WithAttrs(attrs)}
+}
+
+func (this *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: this.Handler.WithGroup(name)}
+}
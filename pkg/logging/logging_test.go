@@ -0,0 +1,70 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"testing"
+)
+
+func TestContextAttributes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(&configuration.ConfigStruct{LogLevel: "info", LogFormat: "json"}, buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx := WithExecutionId(WithScheduleId(WithUser(WithRequestId(context.Background(), "r1"), "u1"), "s1"), "e1")
+	logger.DebugContext(ctx, "filtered")
+	logger.With("component", "test").InfoContext(ctx, "message", "foo", "bar")
+
+	record := map[string]interface{}{}
+	err = json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Error(err, buf.String())
+		return
+	}
+	expected := map[string]interface{}{
+		"level":        "INFO",
+		"msg":          "message",
+		"foo":          "bar",
+		"component":    "test",
+		"request_id":   "r1",
+		"user":         "u1",
+		"schedule_id":  "s1",
+		"execution_id": "e1",
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Error(key, record[key], value)
+		}
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := New(&configuration.ConfigStruct{LogLevel: "verbose"}, &bytes.Buffer{})
+	if err == nil {
+		t.Error("expected error for unknown level")
+	}
+	_, err = New(&configuration.ConfigStruct{LogFormat: "xml"}, &bytes.Buffer{})
+	if err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "process_scheduler"
+
+// Metrics holds the prometheus collectors of one service instance.
+// All methods may be called on a nil *Metrics, which makes metrics optional for tests and tools.
+type Metrics struct {
+	registry           *prometheus.Registry
+	jobs               *prometheus.GaugeVec
+	executions         *prometheus.CounterVec
+	throttled          *prometheus.CounterVec
+	workersBusy        prometheus.Gauge
+	queueDepth         *prometheus.GaugeVec
+	queueWait          *prometheus.HistogramVec
+	queueDropped       *prometheus.CounterVec
+	queueCoalesced     *prometheus.CounterVec
+	executionLatency   prometheus.Histogram
+	schedulingLag      prometheus.Histogram
+	persistenceLatency *prometheus.HistogramVec
+	reconciledEntries  prometheus.Counter
+	httpRequests       *prometheus.CounterVec
+	httpLatency        *prometheus.HistogramVec
+	httpResponseSize   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	result := &Metrics{
+		registry: prometheus.NewRegistry(),
+		jobs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "jobs",
+			Help:      "number of known schedule entries by state (total, enabled, disabled, paused)",
+		}, []string{"state"}),
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "executions_total",
+			Help:      "number of process executions by outcome and status code of the process engine",
+		}, []string{"outcome", "status_code"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "throttled_executions_total",
+			Help:      "number of firings that exceeded an execution rate limit by scope (global, user) and outcome (queued, deferred, dropped)",
+		}, []string{"scope", "outcome"}),
+		workersBusy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "execution_workers_busy",
+			Help:      "number of workers of the execution pool that are starting a process",
+		}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "execution_queue_depth",
+			Help:      "number of firings waiting for a worker of the execution pool by priority",
+		}, []string{"priority"}),
+		queueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "execution_queue_wait_seconds",
+			Help:      "time firings waited for a worker of the execution pool by priority",
+			Buckets:   []float64{.001, .01, .1, .5, 1, 5, 10, 30, 60, 300},
+		}, []string{"priority"}),
+		queueDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "execution_queue_dropped_total",
+			Help:      "number of firings dropped because the execution queue was full by priority",
+		}, []string{"priority"}),
+		queueCoalesced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "execution_queue_coalesced_total",
+			Help:      "number of firings coalesced with a waiting firing of the same entry by priority",
+		}, []string{"priority"}),
+		executionLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "execution_duration_seconds",
+			Help:      "duration of process start requests",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		schedulingLag: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "scheduling_lag_seconds",
+			Help:      "delay between the planned and the actual start of an execution",
+			Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30, 60},
+		}),
+		persistenceLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "persistence_operation_duration_seconds",
+			Help:      "duration of persistence operations",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		reconciledEntries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconciled_entries_total",
+			Help:      "number of cron registrations that diverged from the database and have been corrected",
+		}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "number of handled api requests",
+		}, []string{"method", "route", "status_code"}),
+		httpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "duration of api requests",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		httpResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_response_size_bytes",
+			Help:      "size of api responses",
+			Buckets:   prometheus.ExponentialBuckets(100, 10, 6),
+		}, []string{"method", "route"}),
+	}
+	result.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		result.jobs,
+		result.executions,
+		result.throttled,
+		result.workersBusy,
+		result.queueDepth,
+		result.queueWait,
+		result.queueDropped,
+		result.queueCoalesced,
+		result.executionLatency,
+		result.schedulingLag,
+		result.persistenceLatency,
+		result.reconciledEntries,
+		result.httpRequests,
+		result.httpLatency,
+		result.httpResponseSize,
+	)
+	result.SetJobs(0, 0, 0)
+	return result
+}
+
+func (this *Metrics) Handler() http.Handler {
+	if this == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(this.registry, promhttp.HandlerOpts{Registry: this.registry})
+}
+
+// Registry allows other packages to register additional collectors
+func (this *Metrics) Registry() prometheus.Registerer {
+	if this == nil {
+		return prometheus.NewRegistry()
+	}
+	return this.registry
+}
+
+// SetJobs sets the number of entries by state; paused counts entries that are paused but not disabled
+func (this *Metrics) SetJobs(enabled int, disabled int, paused int) {
+	if this == nil {
+		return
+	}
+	this.jobs.WithLabelValues("total").Set(float64(enabled + disabled + paused))
+	this.jobs.WithLabelValues("enabled").Set(float64(enabled))
+	this.jobs.WithLabelValues("disabled").Set(float64(disabled))
+	this.jobs.WithLabelValues("paused").Set(float64(paused))
+}
+
+func (this *Metrics) ObserveExecution(outcome string, statusCode int, duration time.Duration, lag time.Duration) {
+	if this == nil {
+		return
+	}
+	this.executions.WithLabelValues(outcome, strconv.Itoa(statusCode)).Inc()
+	this.executionLatency.Observe(duration.Seconds())
+	if lag >= 0 {
+		this.schedulingLag.Observe(lag.Seconds())
+	}
+}
+
+// ObserveSkippedExecution counts a firing that has been suppressed without calling the process engine
+func (this *Metrics) ObserveSkippedExecution() {
+	if this == nil {
+		return
+	}
+	this.executions.WithLabelValues("skipped", "0").Inc()
+}
+
+// ObserveThrottledExecution counts a firing that exceeded the execution rate limit of scope
+func (this *Metrics) ObserveThrottledExecution(scope string, outcome string) {
+	if this == nil {
+		return
+	}
+	this.throttled.WithLabelValues(scope, outcome).Inc()
+}
+
+// SetWorkersBusy sets the number of busy workers of the execution pool
+func (this *Metrics) SetWorkersBusy(busy int) {
+	if this == nil {
+		return
+	}
+	this.workersBusy.Set(float64(busy))
+}
+
+// SetQueueDepth sets the number of firings of the given priority that wait for a worker
+func (this *Metrics) SetQueueDepth(priority string, depth int) {
+	if this == nil {
+		return
+	}
+	this.queueDepth.WithLabelValues(priority).Set(float64(depth))
+}
+
+// ObserveQueueWait records the time a firing waited for a worker
+func (this *Metrics) ObserveQueueWait(priority string, wait time.Duration) {
+	if this == nil {
+		return
+	}
+	this.queueWait.WithLabelValues(priority).Observe(wait.Seconds())
+}
+
+// ObserveQueueDropped counts a firing that has been dropped because the execution queue was full
+func (this *Metrics) ObserveQueueDropped(priority string) {
+	if this == nil {
+		return
+	}
+	this.queueDropped.WithLabelValues(priority).Inc()
+}
+
+// ObserveQueueCoalesced counts a firing that has been coalesced with a waiting firing of the same entry
+func (this *Metrics) ObserveQueueCoalesced(priority string) {
+	if this == nil {
+		return
+	}
+	this.queueCoalesced.WithLabelValues(priority).Inc()
+}
+
+// ObservePersistence starts a timer for the named persistence operation; the returned function stops it.
+// usage: defer this.metrics.ObservePersistence("get")()
+func (this *Metrics) ObservePersistence(operation string) func() {
+	if this == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		this.persistenceLatency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (this *Metrics) AddReconciledEntries(count int) {
+	if this == nil {
+		return
+	}
+	this.reconciledEntries.Add(float64(count))
+}
+
+func (this *Metrics) ObserveRequest(method string, route string, statusCode int, duration time.Duration, size int) {
+	if this == nil {
+		return
+	}
+	this.httpRequests.WithLabelValues(method, route, strconv.Itoa(statusCode)).Inc()
+	this.httpLatency.WithLabelValues(method, route).Observe(duration.Seconds())
+	this.httpResponseSize.WithLabelValues(method, route).Observe(float64(size))
+}
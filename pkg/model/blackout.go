@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// misfire policies decide what happens with runs that have been suppressed by a blackout window
+const MisfirePolicySkip = "skip"          //suppressed runs are dropped (default)
+const MisfirePolicyFireOnce = "fire_once" //suppressed runs are coalesced into one run at the end of the blackout window
+
+// MAX_BLACKOUT_RECURRENCES limits the number of overlapping recurrences that are merged into one active window
+const MAX_BLACKOUT_RECURRENCES = 1000
+
+var ErrorMixedBlackoutWindow = errors.New("a blackout window is either recurring (cron and duration) or absolute (start and end)")
+var ErrorMissingBlackoutTimes = errors.New("missing blackout window times: set cron and duration or start and end")
+var ErrorInvalidBlackoutDuration = errors.New("invalid duration: expect a positive duration like 2h or 30m")
+var ErrorInvalidBlackoutRange = errors.New("end must be after start")
+
+// BlackoutWindow suppresses all scheduled executions while it is active.
+// Recurring windows start at every firing of Cron and last for Duration (e.g. cron "0 2 * * 0" with duration "2h");
+// absolute windows last from Start to End.
+type BlackoutWindow struct {
+	Id          string     `json:"id" bson:"id"`
+	Description string     `json:"description,omitempty" bson:"description,omitempty"`
+	Cron        string     `json:"cron,omitempty" bson:"cron,omitempty"`
+	Duration    string     `json:"duration,omitempty" bson:"duration,omitempty"`
+	Start       *time.Time `json:"start,omitempty" bson:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty" bson:"end,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+}
+
+func (this BlackoutWindow) Validate() error {
+	recurring := this.Cron != "" || this.Duration != ""
+	absolute := this.Start != nil || this.End != nil
+	switch {
+	case recurring && absolute:
+		return ErrorMixedBlackoutWindow
+	case recurring:
+		if this.Cron == "" {
+			return ErrorMissingCronExpr
+		}
+		_, err := CronParser.Parse(this.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+		duration, err := time.ParseDuration(this.Duration)
+		if err != nil || duration <= 0 {
+			return ErrorInvalidBlackoutDuration
+		}
+		return nil
+	case absolute:
+		if this.Start == nil || this.End == nil {
+			return ErrorMissingBlackoutTimes
+		}
+		if !this.End.After(*this.Start) {
+			return ErrorInvalidBlackoutRange
+		}
+		return nil
+	default:
+		return ErrorMissingBlackoutTimes
+	}
+}
+
+// ActiveUntil returns the end of the window if it is active at t; overlapping recurrences extend the end
+func (this BlackoutWindow) ActiveUntil(t time.Time) (end time.Time, active bool) {
+	if this.Start != nil && this.End != nil {
+		if !t.Before(*this.Start) && t.Before(*this.End) {
+			return *this.End, true
+		}
+		return end, false
+	}
+	schedule, err := CronParser.Parse(this.Cron)
+	if err != nil {
+		return end, false
+	}
+	duration, err := time.ParseDuration(this.Duration)
+	if err != nil || duration <= 0 {
+		return end, false
+	}
+	//the latest start before t would be more intuitive, but cron schedules can only be iterated forward
+	start := schedule.Next(t.Add(-duration))
+	if start.IsZero() || start.After(t) {
+		return end, false
+	}
+	end = start.Add(duration)
+	for i := 0; i < MAX_BLACKOUT_RECURRENCES; i++ {
+		next := schedule.Next(start)
+		if next.IsZero() || next.After(end) {
+			break
+		}
+		start = next
+		end = next.Add(duration)
+	}
+	return end, true
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlackoutWindowValidate(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	cases := []struct {
+		window BlackoutWindow
+		valid  bool
+	}{
+		{BlackoutWindow{Cron: "0 2 * * 0", Duration: "2h"}, true},
+		{BlackoutWindow{Start: &now, End: &later}, true},
+		{BlackoutWindow{}, false},
+		{BlackoutWindow{Cron: "0 2 * * 0"}, false},
+		{BlackoutWindow{Duration: "2h"}, false},
+		{BlackoutWindow{Cron: "0 2 * * 0", Duration: "-2h"}, false},
+		{BlackoutWindow{Cron: "foo", Duration: "2h"}, false},
+		{BlackoutWindow{Start: &now}, false},
+		{BlackoutWindow{Start: &later, End: &now}, false},
+		{BlackoutWindow{Cron: "0 2 * * 0", Duration: "2h", Start: &now, End: &later}, false},
+	}
+	for i, c := range cases {
+		err := c.window.Validate()
+		if (err == nil) != c.valid {
+			t.Error(i, c.window, err)
+		}
+	}
+}
+
+func TestBlackoutWindowActiveUntil(t *testing.T) {
+	at := func(value string) time.Time {
+		result, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	start := at("2024-06-01 10:00")
+	end := at("2024-06-01 12:00")
+	absolute := BlackoutWindow{Start: &start, End: &end}
+	sundayNight := BlackoutWindow{Cron: "0 2 * * 0", Duration: "2h"} //2024-06-02 is a sunday
+	overlapping := BlackoutWindow{Cron: "0,30 10 * * *", Duration: "45m"}
+
+	cases := []struct {
+		window BlackoutWindow
+		t      string
+		active bool
+		end    string
+	}{
+		{absolute, "2024-06-01 09:59", false, ""},
+		{absolute, "2024-06-01 10:00", true, "2024-06-01 12:00"},
+		{absolute, "2024-06-01 11:59", true, "2024-06-01 12:00"},
+		{absolute, "2024-06-01 12:00", false, ""},
+		{sundayNight, "2024-06-02 01:59", false, ""},
+		{sundayNight, "2024-06-02 02:00", true, "2024-06-02 04:00"},
+		{sundayNight, "2024-06-02 03:30", true, "2024-06-02 04:00"},
+		{sundayNight, "2024-06-02 04:00", false, ""},
+		{sundayNight, "2024-06-03 03:00", false, ""},
+		{overlapping, "2024-06-01 10:15", true, "2024-06-01 11:15"}, //the recurrence at 10:30 extends the window
+		{overlapping, "2024-06-01 11:15", false, ""},
+	}
+	for i, c := range cases {
+		end, active := c.window.ActiveUntil(at(c.t))
+		if active != c.active {
+			t.Error(i, c.t, active)
+			continue
+		}
+		if active && !end.Equal(at(c.end)) {
+			t.Error(i, c.t, end, c.end)
+		}
+	}
+}
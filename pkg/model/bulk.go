@@ -0,0 +1,96 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+const BulkOpCreate = "create"
+const BulkOpUpdate = "update"
+const BulkOpDelete = "delete"
+const BulkOpEnable = "enable"
+const BulkOpDisable = "disable"
+
+const MaxBulkOperations = 1000
+
+var ErrorNotApplied = errors.New("not applied because another operation of the atomic bulk request failed")
+var ErrorRolledBack = errors.New("rolled back because another operation of the atomic bulk request failed")
+var ErrorMissingId = errors.New("missing id")
+var ErrorMissingEntry = errors.New("missing entry")
+
+type BulkRequest struct {
+	Atomic     bool            `json:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+type BulkOperation struct {
+	Op      string         `json:"op"`
+	Id      string         `json:"id,omitempty"`
+	Entry   *ScheduleEntry `json:"entry,omitempty"`
+	Version *int64         `json:"version,omitempty"` //optional expected version for update, delete, enable and disable
+}
+
+type BulkResponse struct {
+	Applied bool         `json:"applied"` //false if an atomic request failed and nothing has been applied
+	Results []BulkResult `json:"results"`
+}
+
+type BulkResult struct {
+	Index  int            `json:"index"`
+	Op     string         `json:"op"`
+	Id     string         `json:"id,omitempty"`
+	Status int            `json:"status"`
+	Error  string         `json:"error,omitempty"`
+	Entry  *ScheduleEntry `json:"entry,omitempty"`
+}
+
+func (this *BulkRequest) Validate() error {
+	if len(this.Operations) > MaxBulkOperations {
+		return fmt.Errorf("too many operations: max %v per request", MaxBulkOperations)
+	}
+	return nil
+}
+
+func (this *BulkOperation) Validate() error {
+	switch this.Op {
+	case BulkOpCreate:
+		if this.Entry == nil {
+			return ErrorMissingEntry
+		}
+		return this.Entry.Validate()
+	case BulkOpUpdate:
+		if this.Entry == nil {
+			return ErrorMissingEntry
+		}
+		if this.Id == "" {
+			this.Id = this.Entry.Id
+		}
+		if this.Id == "" {
+			return ErrorMissingId
+		}
+		return this.Entry.ValidateAndEnsureId(this.Id)
+	case BulkOpDelete, BulkOpEnable, BulkOpDisable:
+		if this.Id == "" {
+			return ErrorMissingId
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown op %q; expect one of create, update, delete, enable, disable", this.Op)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const CalendarDateFormat = "2006-01-02"
+
+// MAX_CALENDAR_DATES limits the size of a calendar document
+const MAX_CALENDAR_DATES = 10000
+
+var ErrorInvalidCalendarName = errors.New("invalid calendar name: expect 1-64 letters, digits, '.', '_' or '-'")
+var ErrorTooManyCalendarDates = fmt.Errorf("too many calendar dates; at most %v are allowed", MAX_CALENDAR_DATES)
+var ErrorUnknownCalendar = errors.New("unknown calendar")
+var ErrorCalendarInUse = errors.New("calendar is referenced by schedules")
+
+var calendarNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Calendar is a named list of days of a user; schedules reference calendars by name
+// to skip firings on these days (exclude_calendars) or to fire only on these days (include_calendars).
+type Calendar struct {
+	Name        string    `json:"name" bson:"name"`
+	User        string    `json:"-" bson:"user"`
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	Dates       []string  `json:"dates" bson:"dates"` //YYYY-MM-DD, sorted and without duplicates after Validate
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Validate checks the name and the dates; the dates are normalized (sorted, without duplicates)
+func (this *Calendar) Validate() error {
+	if !calendarNamePattern.MatchString(this.Name) {
+		return ErrorInvalidCalendarName
+	}
+	if len(this.Dates) > MAX_CALENDAR_DATES {
+		return ErrorTooManyCalendarDates
+	}
+	dates := make([]string, 0, len(this.Dates))
+	known := map[string]bool{}
+	for _, date := range this.Dates {
+		date = strings.TrimSpace(date)
+		_, err := time.Parse(CalendarDateFormat, date)
+		if err != nil {
+			return fmt.Errorf("invalid calendar date %q; expect YYYY-MM-DD", date)
+		}
+		if !known[date] {
+			known[date] = true
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	this.Dates = dates
+	return nil
+}
+
+// CalendarNames returns all calendars referenced by the entry
+func (this *ScheduleEntry) CalendarNames() (result []string) {
+	result = append(result, this.ExcludeCalendars...)
+	return append(result, this.IncludeCalendars...)
+}
+
+// CalendarFilter decides on which days an entry may fire
+type CalendarFilter struct {
+	location *time.Location
+	exclude  map[string]string //date -> name of the first calendar excluding it
+	include  map[string]bool   //nil if the entry has no include_calendars
+	included []string
+}
+
+// NewCalendarFilter creates the filter of entry; calendars must contain all calendars referenced by the entry.
+// Days are compared in the time zone of the schedule (see ScheduleEntry.Location).
+func NewCalendarFilter(entry ScheduleEntry, calendars map[string]Calendar) CalendarFilter {
+	result := CalendarFilter{location: entry.Location(), exclude: map[string]string{}}
+	for _, name := range entry.ExcludeCalendars {
+		for _, date := range calendars[name].Dates {
+			if _, ok := result.exclude[date]; !ok {
+				result.exclude[date] = name
+			}
+		}
+	}
+	if len(entry.IncludeCalendars) > 0 {
+		result.include = map[string]bool{}
+		result.included = entry.IncludeCalendars
+		for _, name := range entry.IncludeCalendars {
+			for _, date := range calendars[name].Dates {
+				result.include[date] = true
+			}
+		}
+	}
+	return result
+}
+
+// Allows returns false and the reason if a firing at t is excluded by the calendars
+func (this CalendarFilter) Allows(t time.Time) (allowed bool, reason string) {
+	date := t.In(this.location).Format(CalendarDateFormat)
+	if name, excluded := this.exclude[date]; excluded {
+		return false, "excluded by calendar " + name
+	}
+	if this.include != nil && !this.include[date] {
+		return false, "not included in calendars " + strings.Join(this.included, ", ")
+	}
+	return true, ""
+}
+
+// PlannedRun is a future firing of an entry; skipped runs are suppressed by a calendar or a blackout window
+type PlannedRun struct {
+	Time    time.Time `json:"time"`
+	Skipped bool      `json:"skipped,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarValidate(t *testing.T) {
+	calendar := Calendar{Name: "holidays", Dates: []string{"2024-12-26", " 2024-12-25", "2024-12-26"}}
+	err := calendar.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(calendar.Dates, []string{"2024-12-25", "2024-12-26"}) {
+		t.Error(calendar.Dates)
+	}
+	invalid := []Calendar{
+		{Name: "", Dates: []string{}},
+		{Name: "with space"},
+		{Name: "holidays", Dates: []string{"2024-13-01"}},
+		{Name: "holidays", Dates: []string{"25.12.2024"}},
+	}
+	for i, c := range invalid {
+		if c.Validate() == nil {
+			t.Error(i, c)
+		}
+	}
+}
+
+func TestCalendarFilter(t *testing.T) {
+	calendars := map[string]Calendar{
+		"holidays": {Name: "holidays", Dates: []string{"2024-12-25", "2024-12-26"}},
+		"vacation": {Name: "vacation", Dates: []string{"2024-12-24"}},
+		"closing":  {Name: "closing", Dates: []string{"2024-12-31", "2024-12-25"}},
+	}
+	at := func(value string) time.Time {
+		result, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	exclude := NewCalendarFilter(ScheduleEntry{Cron: "0 8 * * *", ExcludeCalendars: []string{"holidays", "vacation"}}, calendars)
+	include := NewCalendarFilter(ScheduleEntry{Cron: "0 8 * * *", IncludeCalendars: []string{"closing"}}, calendars)
+	both := NewCalendarFilter(ScheduleEntry{Cron: "0 8 * * *", ExcludeCalendars: []string{"holidays"}, IncludeCalendars: []string{"closing"}}, calendars)
+	none := NewCalendarFilter(ScheduleEntry{Cron: "0 8 * * *"}, calendars)
+	cases := []struct {
+		filter  CalendarFilter
+		t       string
+		allowed bool
+		reason  string
+	}{
+		{exclude, "2024-12-23 08:00", true, ""},
+		{exclude, "2024-12-24 08:00", false, "excluded by calendar vacation"},
+		{exclude, "2024-12-25 00:00", false, "excluded by calendar holidays"},
+		{exclude, "2024-12-26 23:59", false, "excluded by calendar holidays"},
+		{include, "2024-12-31 08:00", true, ""},
+		{include, "2024-12-30 08:00", false, "not included in calendars closing"},
+		{both, "2024-12-31 08:00", true, ""},
+		{both, "2024-12-25 08:00", false, "excluded by calendar holidays"},
+		{none, "2024-12-25 08:00", true, ""},
+	}
+	for i, c := range cases {
+		allowed, reason := c.filter.Allows(at(c.t))
+		if allowed != c.allowed || reason != c.reason {
+			t.Error(i, c.t, allowed, reason)
+		}
+	}
+}
+
+func TestCalendarFilterUsesScheduleTimeZone(t *testing.T) {
+	calendars := map[string]Calendar{"holidays": {Name: "holidays", Dates: []string{"2024-12-25"}}}
+	filter := NewCalendarFilter(ScheduleEntry{Cron: "CRON_TZ=Asia/Tokyo 0 8 * * *", ExcludeCalendars: []string{"holidays"}}, calendars)
+	//2024-12-24 23:00 UTC is already 2024-12-25 in Tokyo
+	if allowed, _ := filter.Allows(time.Date(2024, 12, 24, 23, 0, 0, 0, time.UTC)); allowed {
+		t.Error("expected exclusion in schedule time zone")
+	}
+	if allowed, _ := filter.Allows(time.Date(2024, 12, 25, 16, 0, 0, 0, time.UTC)); !allowed {
+		t.Error("expected 2024-12-26 in schedule time zone to be allowed")
+	}
+}
+
+func TestParseICalendar(t *testing.T) {
+	document := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"X-WR-CALNAME:Public holidays",
+		"BEGIN:VEVENT",
+		"SUMMARY:Christmas",
+		"DTSTART;VALUE=DATE:20241225",
+		"DTEND;VALUE=DATE:20241227",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:New Year",
+		"DTSTART;VALUE=DATE:20200101",
+		"RRULE:FREQ=YEARLY",
+		"EXDATE;VALUE=DATE:20210101",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Meeting",
+		"DTSTART;TZID=Europe/Berlin:20240610T220000",
+		"DTEND;TZID=Europe/Berlin:20240611T010000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Cancelled",
+		"DTSTART:20240701T100000Z",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Folded",
+		"DTSTART;VALUE=DATE:2024",
+		" 0801",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	result, err := ParseICalendar([]byte(document), time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Name = "test"
+	err = result.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Description != "Public holidays" {
+		t.Error(result.Description)
+	}
+	//the yearly event is expanded until 10 years after now, without the excluded 2021
+	expected := []string{"2020-01-01"}
+	for year := 2022; year <= 2032; year++ {
+		expected = append(expected, time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).Format(CalendarDateFormat))
+	}
+	expected = append(expected, "2024-06-10", "2024-06-11", "2024-08-01", "2024-12-25", "2024-12-26")
+	if !reflect.DeepEqual(result.Dates, mustValidateDates(t, expected)) {
+		t.Error(result.Dates)
+	}
+}
+
+func TestParseICalendarErrors(t *testing.T) {
+	invalid := []string{
+		"foo",
+		"BEGIN:VEVENT\nDTSTART:20240101\nEND:VEVENT",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:no start\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:2024-01-01\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;TZID=Foo/Bar:20240101T100000\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101\nRRULE:FREQ=FOO\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101\nDTEND:20600101\nEND:VEVENT\nEND:VCALENDAR",
+	}
+	for i, document := range invalid {
+		_, err := ParseICalendar([]byte(document), time.Now())
+		if err == nil {
+			t.Error(i, document)
+		}
+	}
+
+	_, err := ParseICalendar([]byte("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101T000000Z\nRRULE:FREQ=SECONDLY\nEND:VEVENT\nEND:VCALENDAR"), time.Now())
+	if !errors.Is(err, ErrorICalendarFrequency) {
+		t.Error(err)
+	}
+	//every event adds the same days, so only the recurrence limit ends the expansion
+	event := "BEGIN:VEVENT\nDTSTART:20000101\nRRULE:FREQ=DAILY;UNTIL=20201231\nEND:VEVENT\n"
+	_, err = ParseICalendar([]byte("BEGIN:VCALENDAR\n"+strings.Repeat(event, 20)+"END:VCALENDAR"), time.Now())
+	if !errors.Is(err, ErrorTooManyICalendarOccurrences) {
+		t.Error(err)
+	}
+}
+
+func mustValidateDates(t *testing.T, dates []string) []string {
+	calendar := Calendar{Name: "expected", Dates: dates}
+	err := calendar.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calendar.Dates
+}
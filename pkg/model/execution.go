@@ -0,0 +1,48 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+const ExecutionStatusSuccess = "success"
+const ExecutionStatusFailed = "failed"
+const ExecutionStatusSkipped = "skipped"     //the firing has been suppressed; Reason describes why
+const ExecutionStatusThrottled = "throttled" //the firing has been dropped by an execution rate limit or a full execution queue; Reason names the cause
+
+// Execution records a single firing of a ScheduleEntry
+type Execution struct {
+	Id         string    `json:"id" bson:"id"`
+	ScheduleId string    `json:"schedule_id" bson:"schedule_id"`
+	User       string    `json:"-" bson:"user"`
+	Status     string    `json:"status" bson:"status"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	Reason     string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	Delay      string    `json:"delay,omitempty" bson:"delay,omitempty"`         //jitter, spread and rate limit delay between the planned firing and the start (e.g. 1m12.5s)
+	Throttled  bool      `json:"throttled,omitempty" bson:"throttled,omitempty"` //the start has been delayed or prevented by an execution rate limit
+	StartedAt  time.Time `json:"started_at" bson:"started_at"`
+	FinishedAt time.Time `json:"finished_at" bson:"finished_at"`
+	ExpiresAt  time.Time `json:"-" bson:"expires_at"`
+}
+
+// ScheduleEntryInfo is a ScheduleEntry enriched with computed runtime information;
+// the next run is the stored ScheduleEntry.NextRun
+type ScheduleEntryInfo struct {
+	ScheduleEntry
+	LastRun      *Execution `json:"last_run,omitempty"`
+	FailureCount int64      `json:"failure_count"`
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+const ExportDocumentVersion = 1
+
+const ImportStrategySkip = "skip"
+const ImportStrategyOverwrite = "overwrite"
+const ImportStrategyDuplicate = "duplicate"
+
+const ImportActionCreate = "create"
+const ImportActionOverwrite = "overwrite"
+const ImportActionSkip = "skip"
+const ImportActionDuplicate = "duplicate"
+const ImportActionConflict = "conflict" //the id is used by another user
+const ImportActionError = "error"
+
+var ErrorDuplicateId = errors.New("id is already used")
+var ErrorReservedId = errors.New("id is reserved")
+var ErrorInvalidId = errors.New("invalid id: expect 1-64 letters, digits, '_' or '-'")
+
+// ReservedIds can not be used as schedule ids because GET /schedules/:id would dispatch them to a static route
+var ReservedIds = []string{"export"}
+
+// idPattern accepts UUIDs (the ids generated by the service) and other ids that are safe in urls
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidateId checks ids that are chosen by the user (e.g. on import);
+// returns ErrorInvalidId if the id does not match idPattern and ErrorReservedId if the id is one of ReservedIds
+func ValidateId(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrorInvalidId
+	}
+	if slices.Contains(ReservedIds, id) {
+		return fmt.Errorf("%w: %v", ErrorReservedId, id)
+	}
+	return nil
+}
+
+// ExportDocument is the portable representation of a user's schedules.
+// YAML documents use the same field names as the JSON representation.
+type ExportDocument struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Schedules  []ExportedSchedule `json:"schedules"`
+}
+
+// ExportedSchedule contains the user defined fields of a ScheduleEntry;
+// fields that are managed by the service (version, created_at, next_run, last_fired_at) are not exported.
+type ExportedSchedule struct {
+	Id                  string           `json:"id"`
+	Cron                string           `json:"cron"`
+	Rrule               string           `json:"rrule,omitempty"`
+	Solar               *SolarTrigger    `json:"solar,omitempty"`
+	Interval            *IntervalTrigger `json:"interval,omitempty"`
+	ProcessDeploymentId string           `json:"process_deployment_id"`
+	ProcessAlias        *string          `json:"process_alias,omitempty"`
+	Disabled            *bool            `json:"disabled,omitempty"`
+	CreatedBy           *string          `json:"created_by,omitempty"`
+	Tags                []string         `json:"tags,omitempty"`
+	MisfirePolicy       string           `json:"misfire_policy,omitempty"`
+	ExcludeCalendars    []string         `json:"exclude_calendars,omitempty"`
+	IncludeCalendars    []string         `json:"include_calendars,omitempty"`
+	Jitter              string           `json:"jitter,omitempty"`
+	Priority            string           `json:"priority,omitempty"`
+}
+
+type ImportRequest struct {
+	Strategy          string            `json:"strategy"` //skip (default), overwrite or duplicate
+	DryRun            bool              `json:"dry_run"`
+	IdMapping         map[string]string `json:"id_mapping,omitempty"`         //exported id -> id in this environment
+	DeploymentMapping map[string]string `json:"deployment_mapping,omitempty"` //exported process_deployment_id -> process_deployment_id in this environment
+	Document          ExportDocument    `json:"document"`
+}
+
+type ImportResponse struct {
+	DryRun  bool           `json:"dry_run"`
+	Results []ImportResult `json:"results"`
+}
+
+type ImportResult struct {
+	SourceId string `json:"source_id"`
+	TargetId string `json:"target_id,omitempty"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+}
+
+func NewExportedSchedule(entry ScheduleEntry) ExportedSchedule {
+	return ExportedSchedule{
+		Id:                  entry.Id,
+		Cron:                entry.Cron,
+		Rrule:               entry.Rrule,
+		Solar:               entry.Solar,
+		Interval:            entry.Interval,
+		ProcessDeploymentId: entry.ProcessDeploymentId,
+		ProcessAlias:        entry.ProcessAlias,
+		Disabled:            entry.Disabled,
+		CreatedBy:           entry.CreatedBy,
+		Tags:                entry.Tags,
+		MisfirePolicy:       entry.MisfirePolicy,
+		ExcludeCalendars:    entry.ExcludeCalendars,
+		IncludeCalendars:    entry.IncludeCalendars,
+		Jitter:              entry.Jitter,
+		Priority:            entry.Priority,
+	}
+}
+
+func (this ExportedSchedule) ToEntry() ScheduleEntry {
+	return ScheduleEntry{
+		Id:                  this.Id,
+		Cron:                this.Cron,
+		Rrule:               this.Rrule,
+		Solar:               this.Solar,
+		Interval:            this.Interval,
+		ProcessDeploymentId: this.ProcessDeploymentId,
+		ProcessAlias:        this.ProcessAlias,
+		Disabled:            this.Disabled,
+		CreatedBy:           this.CreatedBy,
+		Tags:                this.Tags,
+		MisfirePolicy:       this.MisfirePolicy,
+		ExcludeCalendars:    this.ExcludeCalendars,
+		IncludeCalendars:    this.IncludeCalendars,
+		Jitter:              this.Jitter,
+		Priority:            this.Priority,
+	}
+}
+
+func (this *ImportRequest) Validate() error {
+	switch this.Strategy {
+	case "":
+		this.Strategy = ImportStrategySkip
+	case ImportStrategySkip, ImportStrategyOverwrite, ImportStrategyDuplicate:
+	default:
+		return fmt.Errorf("unknown import strategy %q; expect skip, overwrite or duplicate", this.Strategy)
+	}
+	if this.Document.Version != ExportDocumentVersion {
+		return fmt.Errorf("unsupported document version %v; expect %v", this.Document.Version, ExportDocumentVersion)
+	}
+	return nil
+}
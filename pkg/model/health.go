@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+const HealthStatusUp = "up"
+const HealthStatusDown = "down"
+const HealthStatusNotApplicable = "not_applicable"
+
+const HealthComponentMongo = "mongo"
+const HealthComponentScheduler = "scheduler"
+const HealthComponentLeader = "leader"
+const HealthComponentProcessEndpoint = "process_endpoint"
+
+// HealthReport is the readiness breakdown per component; Status is HealthStatusDown if any component is down
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ComponentHealth struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func NewHealthReport() HealthReport {
+	return HealthReport{Status: HealthStatusUp, Components: map[string]ComponentHealth{}}
+}
+
+// Set adds the component; err marks it (and the whole report) as down
+func (this *HealthReport) Set(component string, err error) {
+	if err != nil {
+		this.Status = HealthStatusDown
+		this.Components[component] = ComponentHealth{Status: HealthStatusDown, Message: err.Error()}
+		return
+	}
+	this.Components[component] = ComponentHealth{Status: HealthStatusUp}
+}
+
+func (this HealthReport) IsUp() bool {
+	return this.Status == HealthStatusUp
+}
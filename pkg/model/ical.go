@@ -0,0 +1,209 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/teambition/rrule-go"
+	"strings"
+	"time"
+)
+
+// ICALENDAR_RECURRENCE_YEARS limits the expansion of recurring events without end (e.g. RRULE:FREQ=YEARLY)
+const ICALENDAR_RECURRENCE_YEARS = 10
+
+// MAX_ICALENDAR_OCCURRENCES limits the recurrences that are expanded for all events of a document together
+const MAX_ICALENDAR_OCCURRENCES = 100000
+
+var ErrorNotICalendar = errors.New("not an iCalendar document: missing BEGIN:VCALENDAR")
+var ErrorICalendarFrequency = errors.New("unsupported RRULE frequency: calendars are day based; expect DAILY, WEEKLY, MONTHLY or YEARLY")
+var ErrorTooManyICalendarOccurrences = fmt.Errorf("too many event recurrences; at most %v are expanded", MAX_ICALENDAR_OCCURRENCES)
+
+type icalEvent struct {
+	start     time.Time
+	end       *time.Time
+	allDay    bool
+	rrule     string
+	exdates   map[string]bool
+	cancelled bool
+}
+
+// ParseICalendar reads the days of all events of an iCalendar (RFC 5545) document.
+// Events cover every day from DTSTART until DTEND (exclusive for all-day events); recurring events (RRULE, EXDATE)
+// are expanded up to ICALENDAR_RECURRENCE_YEARS after now, but at most MAX_ICALENDAR_OCCURRENCES recurrences of all events together. The description is taken from X-WR-CALDESC or X-WR-CALNAME.
+func ParseICalendar(data []byte, now time.Time) (result Calendar, err error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\n ", ""), "\n\t", "")
+	horizon := now.AddDate(ICALENDAR_RECURRENCE_YEARS, 0, 0)
+	calendarFound := false
+	calendarName := ""
+	var event *icalEvent
+	dates := map[string]bool{}
+	budget := MAX_ICALENDAR_OCCURRENCES
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		head, value, found := strings.Cut(line, ":")
+		if !found {
+			return result, fmt.Errorf("invalid iCalendar line %v: %q", i+1, line)
+		}
+		parts := strings.Split(head, ";")
+		name := strings.ToUpper(parts[0])
+		params := map[string]string{}
+		for _, param := range parts[1:] {
+			key, paramValue, _ := strings.Cut(param, "=")
+			params[strings.ToUpper(key)] = strings.Trim(paramValue, `"`)
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			calendarFound = true
+		case name == "X-WR-CALNAME":
+			calendarName = value
+		case name == "X-WR-CALDESC":
+			result.Description = value
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &icalEvent{exdates: map[string]bool{}}
+		case event == nil:
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			err = event.addDates(dates, horizon, &budget)
+			if err != nil {
+				return result, fmt.Errorf("invalid event ending in line %v: %w", i+1, err)
+			}
+			if len(dates) > MAX_CALENDAR_DATES {
+				return result, ErrorTooManyCalendarDates
+			}
+			event = nil
+		case name == "DTSTART":
+			event.start, event.allDay, err = parseICalendarTime(value, params)
+			if err != nil {
+				return result, fmt.Errorf("invalid DTSTART in line %v: %w", i+1, err)
+			}
+		case name == "DTEND":
+			end, _, err := parseICalendarTime(value, params)
+			if err != nil {
+				return result, fmt.Errorf("invalid DTEND in line %v: %w", i+1, err)
+			}
+			event.end = &end
+		case name == "RRULE":
+			event.rrule = value
+		case name == "EXDATE":
+			for _, exdate := range strings.Split(value, ",") {
+				t, _, err := parseICalendarTime(exdate, params)
+				if err != nil {
+					return result, fmt.Errorf("invalid EXDATE in line %v: %w", i+1, err)
+				}
+				event.exdates[t.Format(CalendarDateFormat)] = true
+			}
+		case name == "STATUS":
+			event.cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+	if !calendarFound {
+		return result, ErrorNotICalendar
+	}
+	if result.Description == "" {
+		result.Description = calendarName
+	}
+	result.Dates = make([]string, 0, len(dates))
+	for date := range dates {
+		result.Dates = append(result.Dates, date)
+	}
+	return result, nil
+}
+
+// parseICalendarTime parses DATE (all-day) and DATE-TIME values; local times use the TZID parameter or the local time zone
+func parseICalendarTime(value string, params map[string]string) (result time.Time, allDay bool, err error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		result, err = time.ParseInLocation("20060102", value, time.UTC)
+		return result, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		result, err = time.Parse("20060102T150405Z", value)
+		return result, false, err
+	}
+	location := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		location, err = time.LoadLocation(tzid)
+		if err != nil {
+			return result, false, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+	result, err = time.ParseInLocation("20060102T150405", value, location)
+	return result, false, err
+}
+
+// addDates adds the days of all occurrences of the event; budget is the number of recurrences that may still be expanded
+func (this *icalEvent) addDates(dates map[string]bool, horizon time.Time, budget *int) error {
+	if this.start.IsZero() {
+		return errors.New("missing DTSTART")
+	}
+	if this.cancelled {
+		return nil
+	}
+	if this.rrule == "" {
+		this.addOccurrence(dates, this.start)
+		return nil
+	}
+	option, err := rrule.StrToROptionInLocation(this.rrule, this.start.Location())
+	if err != nil {
+		return fmt.Errorf("invalid RRULE: %w", err)
+	}
+	if option.Freq > rrule.DAILY {
+		return ErrorICalendarFrequency
+	}
+	option.Dtstart = this.start
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return fmt.Errorf("invalid RRULE: %w", err)
+	}
+	next := rule.Iterator()
+	for occurrence, ok := next(); ok && !occurrence.After(horizon); occurrence, ok = next() {
+		*budget--
+		if *budget < 0 {
+			return ErrorTooManyICalendarOccurrences
+		}
+		if !this.exdates[occurrence.Format(CalendarDateFormat)] {
+			this.addOccurrence(dates, occurrence)
+		}
+		if len(dates) > MAX_CALENDAR_DATES {
+			return ErrorTooManyCalendarDates
+		}
+	}
+	return nil
+}
+
+// addOccurrence adds all days of the event starting at start; the end is derived from DTEND of the first occurrence
+func (this *icalEvent) addOccurrence(dates map[string]bool, start time.Time) {
+	end := start
+	if this.end != nil && this.end.After(this.start) {
+		end = start.Add(this.end.Sub(this.start))
+		if this.allDay || end.Equal(time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location())) {
+			//all-day events and events ending at midnight do not cover the day of their end
+			end = end.Add(-time.Nanosecond)
+		}
+	}
+	for day := start; len(dates) <= MAX_CALENDAR_DATES; day = day.AddDate(0, 0, 1) {
+		dates[day.Format(CalendarDateFormat)] = true
+		if day.Format(CalendarDateFormat) >= end.Format(CalendarDateFormat) {
+			return
+		}
+	}
+}
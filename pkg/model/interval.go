@@ -0,0 +1,105 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIN_INTERVAL is the shortest interval of an IntervalTrigger
+const MIN_INTERVAL = time.Second
+
+var ErrorMissingIntervalAnchor = errors.New("missing interval anchor")
+var ErrorInvalidIntervalDuration = fmt.Errorf("invalid interval; expect an ISO-8601 duration like PT90M, PT36H or P1W of at least %v; years and months are not supported", MIN_INTERVAL)
+
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// IntervalTrigger fires at Anchor + k * Every for every integer k; firings before the anchor are skipped.
+// Days and weeks are exact multiples of 24h, so the firings shift by an hour relative to local time on DST changes.
+type IntervalTrigger struct {
+	Every  string    `json:"every" bson:"every"` //ISO-8601 duration without years and months, e.g. PT90M or PT36H
+	Anchor time.Time `json:"anchor" bson:"anchor"`
+}
+
+func (this IntervalTrigger) Validate() error {
+	_, err := this.Schedule(nil)
+	return err
+}
+
+// Schedule returns the cron.Schedule of the trigger; firings at or before lastFiredAt (if not nil) are never returned again,
+// so a clock that is set back does not repeat firings
+func (this IntervalTrigger) Schedule(lastFiredAt *time.Time) (*IntervalSchedule, error) {
+	every, err := ParseIsoDuration(this.Every)
+	if err != nil {
+		return nil, err
+	}
+	if this.Anchor.IsZero() {
+		return nil, ErrorMissingIntervalAnchor
+	}
+	result := &IntervalSchedule{anchor: this.Anchor, every: every}
+	if lastFiredAt != nil {
+		result.lastFiredAt = *lastFiredAt
+	}
+	return result, nil
+}
+
+// ParseIsoDuration parses ISO-8601 durations of weeks, days, hours, minutes and seconds (e.g. PT1H30M or P1DT12H)
+func ParseIsoDuration(value string) (result time.Duration, err error) {
+	match := isoDurationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || value[len(value)-1] == 'T' {
+		return result, ErrorInvalidIntervalDuration
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(strings.Replace(match[i+1], ",", ".", 1), 64)
+		if err != nil {
+			return result, ErrorInvalidIntervalDuration
+		}
+		result += time.Duration(number * float64(unit))
+	}
+	if result < MIN_INTERVAL {
+		return result, ErrorInvalidIntervalDuration
+	}
+	return result, nil
+}
+
+// IntervalSchedule is a cron.Schedule of an IntervalTrigger
+type IntervalSchedule struct {
+	anchor      time.Time
+	every       time.Duration
+	lastFiredAt time.Time
+}
+
+// Next returns the first firing after t (and after the last firing); firings are computed from the anchor, so they do not drift
+func (this *IntervalSchedule) Next(t time.Time) time.Time {
+	if t.Before(this.lastFiredAt) {
+		t = this.lastFiredAt
+	}
+	if t.Before(this.anchor) {
+		return this.anchor.In(t.Location())
+	}
+	periods := t.Sub(this.anchor)/this.every + 1
+	return this.anchor.Add(periods * this.every).In(t.Location())
+}
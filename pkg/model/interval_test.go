@@ -0,0 +1,120 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIsoDuration(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"PT90M", 90 * time.Minute},
+		{"PT36H", 36 * time.Hour},
+		{"P1DT12H", 36 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"PT1H30M15S", time.Hour + 30*time.Minute + 15*time.Second},
+		{"PT1.5S", 1500 * time.Millisecond},
+		{"PT2,5S", 2500 * time.Millisecond},
+	}
+	for _, c := range cases {
+		result, err := ParseIsoDuration(c.value)
+		if err != nil || result != c.expected {
+			t.Error(c.value, result, err)
+		}
+	}
+	for _, value := range []string{"", "P", "PT", "P1Y", "P1M", "PT0S", "PT0.5S", "90m", "PT-5M", "P1H", "PT1D"} {
+		if _, err := ParseIsoDuration(value); err == nil {
+			t.Error("expected error", value)
+		}
+	}
+}
+
+func TestIntervalScheduleNext(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	schedule, err := IntervalTrigger{Every: "PT90M", Anchor: anchor}.Schedule(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		after    time.Time
+		expected time.Time
+	}{
+		{anchor.Add(-time.Hour), anchor},
+		{anchor, anchor.Add(90 * time.Minute)},
+		{anchor.Add(time.Minute), anchor.Add(90 * time.Minute)},
+		{anchor.Add(90 * time.Minute), anchor.Add(180 * time.Minute)},
+		{anchor.Add(1000*90*time.Minute + time.Nanosecond), anchor.Add(1001 * 90 * time.Minute)},
+	}
+	for i, c := range cases {
+		if next := schedule.Next(c.after); !next.Equal(c.expected) {
+			t.Error(i, next, c.expected)
+		}
+	}
+}
+
+func TestIntervalScheduleDoesNotDrift(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	schedule, err := IntervalTrigger{Every: "PT36H", Anchor: anchor}.Schedule(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//each firing is handled with a delay; the next firing is computed from the handling time
+	next := anchor
+	for i := 1; i <= 100; i++ {
+		next = schedule.Next(next.Add(time.Duration(i) * time.Second))
+		if !next.Equal(anchor.Add(time.Duration(i) * 36 * time.Hour)) {
+			t.Fatal(i, next)
+		}
+	}
+}
+
+func TestIntervalScheduleLastFiredAt(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	lastFiredAt := anchor.Add(3 * time.Hour)
+	entry := ScheduleEntry{Interval: &IntervalTrigger{Every: "PT1H", Anchor: anchor}, ProcessDeploymentId: "d", LastFiredAt: &lastFiredAt}
+	//e.g. after a restart with a clock that is behind
+	next := entry.GetNextRun(anchor.Add(90 * time.Minute))
+	if next == nil || !next.Equal(anchor.Add(4*time.Hour)) {
+		t.Error(next)
+	}
+	next = entry.GetNextRun(anchor.Add(5*time.Hour + time.Minute))
+	if next == nil || !next.Equal(anchor.Add(6*time.Hour)) {
+		t.Error(next)
+	}
+}
+
+func TestIntervalTriggerValidate(t *testing.T) {
+	anchor := time.Now()
+	cases := []struct {
+		entry ScheduleEntry
+		valid bool
+	}{
+		{ScheduleEntry{Interval: &IntervalTrigger{Every: "PT90M", Anchor: anchor}, ProcessDeploymentId: "d"}, true},
+		{ScheduleEntry{Interval: &IntervalTrigger{Every: "PT90M"}, ProcessDeploymentId: "d"}, false},
+		{ScheduleEntry{Interval: &IntervalTrigger{Every: "P1M", Anchor: anchor}, ProcessDeploymentId: "d"}, false},
+		{ScheduleEntry{Interval: &IntervalTrigger{Every: "PT90M", Anchor: anchor}, Cron: "* * * * *", ProcessDeploymentId: "d"}, false},
+	}
+	for i, c := range cases {
+		if err := c.entry.Validate(); (err == nil) != c.valid {
+			t.Error(i, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+const SortById = "id"
+const SortByAlias = "alias"
+const SortByCreatedAt = "created_at"
+const SortByNextRun = "next_run"
+
+// DEFAULT_LIST_LIMIT is the page size of list requests without limit parameter
+const DEFAULT_LIST_LIMIT = 100
+
+// MAX_LIST_LIMIT is the largest page size of list requests
+const MAX_LIST_LIMIT = 1000
+
+var ErrorInvalidSort = errors.New("invalid sort; expect <id|alias|created_at|next_run>[.asc|.desc]")
+
+type ListOptions struct {
+	CreatedBy           *string
+	ProcessDeploymentId *string
+	Disabled            *bool
+	Paused              *bool
+	AliasSearch         string
+	Tags                []string //entries must have all given tags
+	SortBy              string
+	SortDesc            bool
+	Limit               int64 //0 = unlimited; only used internally, the api limits pages to MAX_LIST_LIMIT
+	Offset              int64
+}
+
+// ParseSort parses sort strings like "alias" or "created_at.desc"
+func (this *ListOptions) ParseSort(sort string) error {
+	if sort == "" {
+		this.SortBy = SortById
+		return nil
+	}
+	field, direction, _ := strings.Cut(sort, ".")
+	switch field {
+	case SortById, SortByAlias, SortByCreatedAt, SortByNextRun:
+		this.SortBy = field
+	default:
+		return ErrorInvalidSort
+	}
+	switch direction {
+	case "", "asc":
+		this.SortDesc = false
+	case "desc":
+		this.SortDesc = true
+	default:
+		return ErrorInvalidSort
+	}
+	return nil
+}
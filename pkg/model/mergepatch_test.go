@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+// examples from RFC 7396 Appendix A
+func TestApplyMergePatch(t *testing.T) {
+	cases := []struct {
+		original string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"n":12345678901234567890}`, `{"a":1}`, `{"n":12345678901234567890,"a":1}`},
+	}
+	for _, c := range cases {
+		result, err := ApplyMergePatch([]byte(c.original), []byte(c.patch))
+		if err != nil {
+			t.Error(c, err)
+			continue
+		}
+		var actual, expected interface{}
+		if err = decodeJsonWithNumbers(result, &actual); err != nil {
+			t.Error(c, err)
+			continue
+		}
+		if err = decodeJsonWithNumbers([]byte(c.expected), &expected); err != nil {
+			t.Error(c, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Error(c, string(result))
+		}
+	}
+}
+
+func TestApplyMergePatchInvalid(t *testing.T) {
+	_, err := ApplyMergePatch([]byte(`{"a":"b"}`), []byte(`{"a":`))
+	if err == nil {
+		t.Error("expected error")
+	}
+}
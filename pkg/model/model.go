@@ -20,42 +20,206 @@ import (
 	"errors"
 	"fmt"
 	"github.com/robfig/cron/v3"
+	"time"
 )
 
 type ScheduleEntry struct {
-	Id                  string  `json:"id" bson:"id"`
-	User                string  `json:"-" bson:"user"`
-	Cron                string  `json:"cron" bson:"cron"`
-	ProcessDeploymentId string  `json:"process_deployment_id" bson:"process_deployment_id"`
-	ProcessAlias        *string `json:"process_alias,omitempty" bson:"process_alias"`
-	Disabled            *bool   `json:"disabled,omitempty" bson:"disabled"`
-	CreatedBy           *string `json:"created_by,omitempty" bson:"created_by"`
+	Id                  string           `json:"id" bson:"id"`
+	User                string           `json:"-" bson:"user"`
+	Cron                string           `json:"cron" bson:"cron"`
+	Rrule               string           `json:"rrule,omitempty" bson:"rrule,omitempty"`       //RFC 5545 recurrence (DTSTART, RRULE, RDATE and EXDATE lines); alternative to cron
+	Solar               *SolarTrigger    `json:"solar,omitempty" bson:"solar,omitempty"`       //solar event at a position; alternative to cron
+	Interval            *IntervalTrigger `json:"interval,omitempty" bson:"interval,omitempty"` //fixed interval from an anchor; alternative to cron
+	ProcessDeploymentId string           `json:"process_deployment_id" bson:"process_deployment_id"`
+	ProcessAlias        *string          `json:"process_alias,omitempty" bson:"process_alias"`
+	Disabled            *bool            `json:"disabled,omitempty" bson:"disabled"`
+	CreatedBy           *string          `json:"created_by,omitempty" bson:"created_by"`
+	Tags                []string         `json:"tags,omitempty" bson:"tags,omitempty"`
+	Version             int64            `json:"version" bson:"version"`
+	CreatedAt           time.Time        `json:"created_at" bson:"created_at"`
+	NextRun             *time.Time       `json:"next_run,omitempty" bson:"next_run"`                             //stored on changes and firings; with multiple instances it may briefly lag behind the firings of another instance
+	LastFiredAt         *time.Time       `json:"last_fired_at,omitempty" bson:"last_fired_at,omitempty"`         //planned time of the last firing (including skipped ones); managed by the service
+	Paused              bool             `json:"paused,omitempty" bson:"paused"`                                 //set by pause/resume without changing Version; independent of Disabled
+	MisfirePolicy       string           `json:"misfire_policy,omitempty" bson:"misfire_policy,omitempty"`       //skip (default) or fire_once
+	ExcludeCalendars    []string         `json:"exclude_calendars,omitempty" bson:"exclude_calendars,omitempty"` //names of calendars with days on which the entry does not fire
+	IncludeCalendars    []string         `json:"include_calendars,omitempty" bson:"include_calendars,omitempty"` //if set, the entry fires only on days of one of these calendars
+	Jitter              string           `json:"jitter,omitempty" bson:"jitter,omitempty"`                       //max random delay of each firing (e.g. 30s or 5m)
+	Priority            string           `json:"priority,omitempty" bson:"priority,omitempty"`                   //high, normal (default) or low; orders firings that wait for a worker
 }
 
+var CronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
 var ErrorMissingCronExpr = errors.New("missing cron expression")
+var ErrorMissingTrigger = errors.New("missing trigger: set cron, rrule, solar or interval")
+var ErrorAmbiguousTrigger = errors.New("ambiguous trigger: set only one of cron, rrule, solar and interval")
 var ErrorMissingProcessDeploymentId = errors.New("missing process_deployment_id")
 var ErrorIdMissmatch = errors.New("path id does not match body id")
 var ErrorNotFound = errors.New("not found")
 var ErrorAccessDenied = errors.New("access denied")
+var ErrorVersionConflict = errors.New("version conflict: schedule has been modified concurrently")
+var ErrorInvalidVersion = errors.New("invalid If-Match header")
+var ErrorShuttingDown = errors.New("service is shutting down")
+
+const MAX_JITTER = time.Hour
+
+var ErrorInvalidJitter = fmt.Errorf("invalid jitter: expect a duration like 30s or 5m of at most %v", MAX_JITTER)
 
 func (this *ScheduleEntry) Validate() error {
-	if this.Cron == "" {
-		return ErrorMissingCronExpr
+	switch this.triggerCount() {
+	case 0:
+		return ErrorMissingTrigger
+	case 1:
+	default:
+		return ErrorAmbiguousTrigger
 	}
 	if this.ProcessDeploymentId == "" {
 		return ErrorMissingProcessDeploymentId
 	}
 
-	_, err := cron.NewParser(
-		cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
-	).Parse(this.Cron)
-	if err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+	switch {
+	case this.Rrule != "":
+		schedule, err := ParseRrule(this.Rrule)
+		if err == nil {
+			err = schedule.Validate(time.Now())
+		}
+		if err != nil {
+			return fmt.Errorf("invalid rrule: %w", err)
+		}
+	case this.Solar != nil:
+		err := this.Solar.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid solar trigger: %w", err)
+		}
+	case this.Interval != nil:
+		err := this.Interval.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid interval trigger: %w", err)
+		}
+	default:
+		_, err := CronParser.Parse(this.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	}
+
+	switch this.MisfirePolicy {
+	case "", MisfirePolicySkip, MisfirePolicyFireOnce:
+	default:
+		return fmt.Errorf("unknown misfire_policy %q; expect skip or fire_once", this.MisfirePolicy)
+	}
+
+	for _, name := range this.CalendarNames() {
+		if !calendarNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: %q", ErrorInvalidCalendarName, name)
+		}
+	}
+
+	if _, err := this.GetJitter(); err != nil {
+		return err
+	}
+
+	if err := this.ValidatePriority(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// triggerCount returns the number of set triggers; a valid entry has exactly one
+func (this *ScheduleEntry) triggerCount() (result int) {
+	if this.Cron != "" {
+		result++
+	}
+	if this.Rrule != "" {
+		result++
+	}
+	if this.Solar != nil {
+		result++
+	}
+	if this.Interval != nil {
+		result++
+	}
+	return result
+}
+
+func (this *ScheduleEntry) IsDisabled() bool {
+	return this.Disabled != nil && *this.Disabled
+}
+
+func (this *ScheduleEntry) GetMisfirePolicy() string {
+	if this.MisfirePolicy == "" {
+		return MisfirePolicySkip
+	}
+	return this.MisfirePolicy
+}
+
+// GetJitter returns the max random delay of each firing; 0 if no jitter is set
+func (this *ScheduleEntry) GetJitter() (time.Duration, error) {
+	if this.Jitter == "" {
+		return 0, nil
+	}
+	result, err := time.ParseDuration(this.Jitter)
+	if err != nil || result < 0 || result > MAX_JITTER {
+		return 0, ErrorInvalidJitter
+	}
+	return result, nil
+}
+
+// IsScheduled returns true if the entry should fire (neither disabled nor paused)
+func (this *ScheduleEntry) IsScheduled() bool {
+	return !this.IsDisabled() && !this.Paused
+}
+
+// Schedule returns the cron.Schedule describing when the entry should fire
+func (this *ScheduleEntry) Schedule() (cron.Schedule, error) {
+	if this.Rrule != "" {
+		return ParseRrule(this.Rrule)
+	}
+	if this.Solar != nil {
+		return this.Solar.Schedule()
+	}
+	if this.Interval != nil {
+		return this.Interval.Schedule(this.LastFiredAt)
+	}
+	return CronParser.Parse(this.Cron)
+}
+
+// Location returns the time zone in which the schedule is evaluated (CRON_TZ, the time zone of DTSTART or the local time zone);
+// solar and interval triggers use the local time zone
+func (this *ScheduleEntry) Location() *time.Location {
+	schedule, err := this.Schedule()
+	if err != nil {
+		return time.Local
+	}
+	switch s := schedule.(type) {
+	case *cron.SpecSchedule:
+		if s.Location != nil {
+			return s.Location
+		}
+	case *RruleSchedule:
+		return s.Location()
+	}
+	return time.Local
+}
+
+// GetNextRun returns the next planned run after the given time; nil if the entry is disabled, paused or will never fire again
+func (this *ScheduleEntry) GetNextRun(after time.Time) *time.Time {
+	if !this.IsScheduled() {
+		return nil
+	}
+	schedule, err := this.Schedule()
+	if err != nil {
+		return nil
+	}
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
 func (this *ScheduleEntry) ValidateAndEnsureId(pathId string) error {
 	if err := this.Validate(); err != nil {
 		return err
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrorMissingSelector = errors.New("missing selector: set all, process_deployment_id or tags")
+var ErrorAmbiguousSelector = errors.New("all can not be combined with process_deployment_id or tags")
+
+// PauseSelector selects the entries of the requesting user that are paused or resumed.
+// process_deployment_id and tags may be combined; entries must match both.
+type PauseSelector struct {
+	All                 bool     `json:"all,omitempty" bson:"all"`
+	ProcessDeploymentId string   `json:"process_deployment_id,omitempty" bson:"process_deployment_id"`
+	Tags                []string `json:"tags,omitempty" bson:"tags"` //entries must have all given tags
+}
+
+// PauseRecord is a stored pause of a user. Pauses may overlap: an entry stays paused
+// as long as at least one record of its user selects it (see IsPaused).
+type PauseRecord struct {
+	Key      string        `json:"-" bson:"key"` //PauseSelector.Key; unique per user
+	User     string        `json:"-" bson:"user"`
+	Selector PauseSelector `json:"selector" bson:"selector"`
+	PausedAt time.Time     `json:"paused_at" bson:"paused_at"`
+}
+
+type PauseResponse struct {
+	Ids    []string          `json:"ids"`              //entries whose paused state has been changed
+	Failed map[string]string `json:"failed,omitempty"` //entry id -> error
+}
+
+func (this PauseSelector) Validate() error {
+	filtered := this.ProcessDeploymentId != "" || len(this.Tags) > 0
+	if this.All && filtered {
+		return ErrorAmbiguousSelector
+	}
+	if !this.All && !filtered {
+		return ErrorMissingSelector
+	}
+	return nil
+}
+
+// ListOptions returns the options to list the entries that are selected and not yet in the target paused state
+func (this PauseSelector) ListOptions(paused bool) ListOptions {
+	result := ListOptions{SortBy: SortById, Tags: this.Tags}
+	if this.ProcessDeploymentId != "" {
+		result.ProcessDeploymentId = &this.ProcessDeploymentId
+	}
+	notPaused := !paused
+	result.Paused = &notPaused
+	return result
+}
+
+// Key identifies the selector independent of the order and duplicates of its tags;
+// resume removes the pause record with the same key
+func (this PauseSelector) Key() string {
+	if this.All {
+		return "all"
+	}
+	tags := slices.Clone(this.Tags)
+	sort.Strings(tags)
+	tags = slices.Compact(tags)
+	return "deployment:" + this.ProcessDeploymentId + "/tags:" + strings.Join(tags, ",")
+}
+
+// Matches returns true if the entry is selected
+func (this PauseSelector) Matches(entry ScheduleEntry) bool {
+	if this.All {
+		return true
+	}
+	if this.ProcessDeploymentId != "" && this.ProcessDeploymentId != entry.ProcessDeploymentId {
+		return false
+	}
+	for _, tag := range this.Tags {
+		if !slices.Contains(entry.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPaused returns true if any of the pause records of the entry user selects the entry
+func IsPaused(records []PauseRecord, entry ScheduleEntry) bool {
+	for _, record := range records {
+		if record.User == entry.User && record.Selector.Matches(entry) {
+			return true
+		}
+	}
+	return false
+}
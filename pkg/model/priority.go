@@ -0,0 +1,69 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Priority classes of firings; if all workers are busy, waiting firings of a higher class are started first
+const PriorityHigh = "high"
+const PriorityNormal = "normal"
+const PriorityLow = "low"
+
+// Priorities lists the priority classes from the highest to the lowest
+var Priorities = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// PriorityRank returns the index of priority in Priorities; unknown priorities rank as PriorityNormal
+func PriorityRank(priority string) int {
+	for i, p := range Priorities {
+		if p == priority {
+			return i
+		}
+	}
+	return PriorityRank(PriorityNormal)
+}
+
+var ErrorInvalidPriority = errors.New("invalid priority: expect high, normal or low")
+var ErrorPriorityForbidden = errors.New("priority not allowed")
+
+// GetPriority returns the priority class of the entry; PriorityNormal if no priority is set
+func (this *ScheduleEntry) GetPriority() string {
+	if this.Priority == "" {
+		return PriorityNormal
+	}
+	return this.Priority
+}
+
+// ValidatePriority returns ErrorInvalidPriority if the priority is unknown
+func (this *ScheduleEntry) ValidatePriority() error {
+	switch this.Priority {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return nil
+	default:
+		return ErrorInvalidPriority
+	}
+}
+
+// ValidateMaxPriority returns ErrorPriorityForbidden if the priority of the entry ranks above max
+func (this *ScheduleEntry) ValidateMaxPriority(max string) error {
+	if PriorityRank(this.GetPriority()) < PriorityRank(max) {
+		return fmt.Errorf("%w: the highest allowed priority is %v", ErrorPriorityForbidden, max)
+	}
+	return nil
+}
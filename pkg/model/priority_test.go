@@ -0,0 +1,61 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	entry := ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "d"}
+	if entry.GetPriority() != PriorityNormal || PriorityRank(entry.GetPriority()) != 1 {
+		t.Error(entry.GetPriority())
+	}
+	if PriorityRank(PriorityHigh) >= PriorityRank(PriorityNormal) || PriorityRank(PriorityNormal) >= PriorityRank(PriorityLow) {
+		t.Error("unexpected order", Priorities)
+	}
+	entry.Priority = "urgent"
+	if err := entry.Validate(); err != ErrorInvalidPriority {
+		t.Error(err)
+	}
+	entry.Priority = PriorityLow
+	if err := entry.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateMaxPriority(t *testing.T) {
+	cases := []struct {
+		priority string
+		max      string
+		allowed  bool
+	}{
+		{"", PriorityNormal, true},
+		{PriorityHigh, PriorityNormal, false},
+		{PriorityHigh, PriorityHigh, true},
+		{PriorityNormal, PriorityLow, false},
+		{PriorityLow, PriorityLow, true},
+	}
+	for _, c := range cases {
+		entry := ScheduleEntry{Priority: c.priority}
+		err := entry.ValidateMaxPriority(c.max)
+		if c.allowed != (err == nil) || (err != nil && !errors.Is(err, ErrorPriorityForbidden)) {
+			t.Error(c, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const QuotaUnlimited = -1
+
+var ErrorQuotaExceeded = errors.New("quota exceeded")
+var ErrorQuotaForbidden = errors.New("forbidden by quota")
+var ErrorQuotaLocked = errors.New("quota is locked by a concurrent change of the user; retry later")
+var ErrorInvalidQuota = errors.New("invalid quota: expect a limit of at least 0, -1 (unlimited) or null (default)")
+var ErrorUserMissmatch = errors.New("path user does not match body user")
+
+// Quota limits the schedules of a user; nil limits are unlimited
+type Quota struct {
+	MaxSchedules              *int64 `json:"max_schedules"`
+	MaxEnabledSchedules       *int64 `json:"max_enabled_schedules"`
+	MaxSchedulesPerDeployment *int64 `json:"max_schedules_per_deployment"`
+}
+
+// QuotaOverride replaces the default quota for one user; nil limits use the default, QuotaUnlimited removes the limit
+type QuotaOverride struct {
+	User                      string    `json:"user" bson:"user"`
+	MaxSchedules              *int64    `json:"max_schedules" bson:"max_schedules"`
+	MaxEnabledSchedules       *int64    `json:"max_enabled_schedules" bson:"max_enabled_schedules"`
+	MaxSchedulesPerDeployment *int64    `json:"max_schedules_per_deployment" bson:"max_schedules_per_deployment"`
+	UpdatedBy                 string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt                 time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// QuotaStatus describes the effective quota of a user and the current usage
+type QuotaStatus struct {
+	Quota            Quota          `json:"quota"`
+	Override         *QuotaOverride `json:"override,omitempty"`
+	Schedules        int64          `json:"schedules"`
+	EnabledSchedules int64          `json:"enabled_schedules"`
+}
+
+// ScheduleCounts is the usage of a user that is limited by Quota; Deployment counts the schedules of one process deployment
+type ScheduleCounts struct {
+	Total      int64
+	Enabled    int64
+	Deployment int64
+}
+
+func (this QuotaOverride) Validate() error {
+	for _, limit := range []*int64{this.MaxSchedules, this.MaxEnabledSchedules, this.MaxSchedulesPerDeployment} {
+		if limit != nil && *limit < QuotaUnlimited {
+			return ErrorInvalidQuota
+		}
+	}
+	return nil
+}
+
+// Apply returns the default quota with the limits of the override
+func (this QuotaOverride) Apply(defaults Quota) Quota {
+	override := func(value *int64, limit *int64) *int64 {
+		switch {
+		case limit == nil:
+			return value
+		case *limit == QuotaUnlimited:
+			return nil
+		default:
+			return limit
+		}
+	}
+	return Quota{
+		MaxSchedules:              override(defaults.MaxSchedules, this.MaxSchedules),
+		MaxEnabledSchedules:       override(defaults.MaxEnabledSchedules, this.MaxEnabledSchedules),
+		MaxSchedulesPerDeployment: override(defaults.MaxSchedulesPerDeployment, this.MaxSchedulesPerDeployment),
+	}
+}
+
+// Check returns ErrorQuotaExceeded or ErrorQuotaForbidden if storing entry would exceed the quota;
+// old is the stored version of entry (nil for new entries), counts the usage before the change.
+// Only limits of counts that are increased by the change are checked.
+func (this Quota) Check(counts ScheduleCounts, entry ScheduleEntry, old *ScheduleEntry) error {
+	if old == nil {
+		err := checkLimit(this.MaxSchedules, counts.Total, "schedules")
+		if err != nil {
+			return err
+		}
+	}
+	if !entry.IsDisabled() && (old == nil || old.IsDisabled()) {
+		err := checkLimit(this.MaxEnabledSchedules, counts.Enabled, "enabled schedules")
+		if err != nil {
+			return err
+		}
+	}
+	if old == nil || old.ProcessDeploymentId != entry.ProcessDeploymentId {
+		err := checkLimit(this.MaxSchedulesPerDeployment, counts.Deployment, "schedules of process deployment "+entry.ProcessDeploymentId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkLimit(limit *int64, count int64, description string) error {
+	switch {
+	case limit == nil:
+		return nil
+	case *limit == 0:
+		return fmt.Errorf("%w: no %v allowed", ErrorQuotaForbidden, description)
+	case count >= *limit:
+		return fmt.Errorf("%w: at most %v %v allowed", ErrorQuotaExceeded, *limit, description)
+	default:
+		return nil
+	}
+}
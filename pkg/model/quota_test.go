@@ -0,0 +1,83 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func limit(value int64) *int64 {
+	return &value
+}
+
+func TestQuotaOverrideApply(t *testing.T) {
+	defaults := Quota{MaxSchedules: limit(10), MaxEnabledSchedules: limit(5)}
+	result := QuotaOverride{MaxSchedules: limit(QuotaUnlimited), MaxSchedulesPerDeployment: limit(2)}.Apply(defaults)
+	if result.MaxSchedules != nil {
+		t.Error("expected unlimited schedules", *result.MaxSchedules)
+	}
+	if result.MaxEnabledSchedules == nil || *result.MaxEnabledSchedules != 5 {
+		t.Error("expected default enabled limit", result.MaxEnabledSchedules)
+	}
+	if result.MaxSchedulesPerDeployment == nil || *result.MaxSchedulesPerDeployment != 2 {
+		t.Error("expected overridden deployment limit", result.MaxSchedulesPerDeployment)
+	}
+}
+
+func TestQuotaOverrideValidate(t *testing.T) {
+	if err := (QuotaOverride{MaxSchedules: limit(-2)}).Validate(); !errors.Is(err, ErrorInvalidQuota) {
+		t.Error(err)
+	}
+	if err := (QuotaOverride{MaxSchedules: limit(QuotaUnlimited), MaxEnabledSchedules: limit(0)}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuotaCheck(t *testing.T) {
+	disabled := true
+	enabledEntry := ScheduleEntry{ProcessDeploymentId: "a"}
+	disabledEntry := ScheduleEntry{ProcessDeploymentId: "a", Disabled: &disabled}
+	movedEntry := ScheduleEntry{ProcessDeploymentId: "b"}
+	quota := Quota{MaxSchedules: limit(2), MaxEnabledSchedules: limit(1), MaxSchedulesPerDeployment: limit(1)}
+	cases := []struct {
+		name     string
+		quota    Quota
+		counts   ScheduleCounts
+		entry    ScheduleEntry
+		old      *ScheduleEntry
+		expected error
+	}{
+		{"unlimited", Quota{}, ScheduleCounts{Total: 100, Enabled: 100, Deployment: 100}, enabledEntry, nil, nil},
+		{"create", quota, ScheduleCounts{}, enabledEntry, nil, nil},
+		{"create above total", quota, ScheduleCounts{Total: 2}, disabledEntry, nil, ErrorQuotaExceeded},
+		{"create above enabled", quota, ScheduleCounts{Total: 1, Enabled: 1}, enabledEntry, nil, ErrorQuotaExceeded},
+		{"create disabled", quota, ScheduleCounts{Total: 1, Enabled: 1}, ScheduleEntry{ProcessDeploymentId: "b", Disabled: &disabled}, nil, nil},
+		{"create above deployment", quota, ScheduleCounts{Total: 1, Deployment: 1}, disabledEntry, nil, ErrorQuotaExceeded},
+		{"update at limits", quota, ScheduleCounts{Total: 2, Enabled: 1, Deployment: 1}, enabledEntry, &enabledEntry, nil},
+		{"enable above limit", quota, ScheduleCounts{Total: 2, Enabled: 1, Deployment: 1}, enabledEntry, &disabledEntry, ErrorQuotaExceeded},
+		{"disable at limit", quota, ScheduleCounts{Total: 2, Enabled: 1, Deployment: 1}, disabledEntry, &enabledEntry, nil},
+		{"move above deployment limit", quota, ScheduleCounts{Total: 2, Enabled: 1, Deployment: 1}, movedEntry, &enabledEntry, ErrorQuotaExceeded},
+		{"forbidden", Quota{MaxEnabledSchedules: limit(0)}, ScheduleCounts{}, enabledEntry, nil, ErrorQuotaForbidden},
+	}
+	for _, c := range cases {
+		err := c.quota.Check(c.counts, c.entry, c.old)
+		if !errors.Is(err, c.expected) || (c.expected == nil && err != nil) {
+			t.Error(c.name, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const MIN_INTERVAL_SAMPLES = 100
+
+const DefaultRole = "default"
+
+var ErrorIntervalTooShort = errors.New("schedule fires too often")
+
+// ShortestInterval returns the smallest gap between the next firings after the given time;
+// 0 if the entry fires less than twice
+func (this *ScheduleEntry) ShortestInterval(after time.Time) (result time.Duration) {
+	schedule, err := this.Schedule()
+	if err != nil {
+		return 0
+	}
+	previous := schedule.Next(after)
+	for i := 0; i < MIN_INTERVAL_SAMPLES && !previous.IsZero(); i++ {
+		next := schedule.Next(previous)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(previous); result == 0 || gap < result {
+			result = gap
+		}
+		previous = next
+	}
+	return result
+}
+
+// ValidateMinInterval returns ErrorIntervalTooShort if two firings of the entry are less than min apart
+func (this *ScheduleEntry) ValidateMinInterval(min time.Duration) error {
+	if min <= 0 {
+		return nil
+	}
+	shortest := this.ShortestInterval(time.Now())
+	if shortest > 0 && shortest < min {
+		return fmt.Errorf("%w: firings are %v apart; the minimum is %v", ErrorIntervalTooShort, shortest, min)
+	}
+	return nil
+}
+
+// SameTrigger checks if both entries fire at the same times
+func (this *ScheduleEntry) SameTrigger(other ScheduleEntry) bool {
+	return this.Cron == other.Cron &&
+		this.Rrule == other.Rrule &&
+		equalPtr(this.Solar, other.Solar) &&
+		equalPtr(this.Interval, other.Interval)
+}
+
+func equalPtr[T comparable](a *T, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+type rolesKey struct{}
+
+// WithRoles stores the roles of the requesting user in ctx
+func WithRoles(ctx context.Context, roles []string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// GetRoles returns the roles stored by WithRoles
+func GetRoles(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey{}).([]string)
+	return roles
+}
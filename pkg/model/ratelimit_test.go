@@ -0,0 +1,80 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShortestInterval(t *testing.T) {
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		entry    ScheduleEntry
+		expected time.Duration
+	}{
+		{ScheduleEntry{Cron: "* * * * * *"}, time.Second},
+		{ScheduleEntry{Cron: "*/5 * * * *"}, 5 * time.Minute},
+		{ScheduleEntry{Cron: "0,1 3 * * *"}, time.Minute},
+		{ScheduleEntry{Interval: &IntervalTrigger{Every: "PT90M", Anchor: after}}, 90 * time.Minute},
+		{ScheduleEntry{Rrule: "DTSTART:20240101T090000Z\nRRULE:FREQ=DAILY;COUNT=1"}, 0},
+		{ScheduleEntry{Cron: "invalid"}, 0},
+	}
+	for _, c := range cases {
+		if result := c.entry.ShortestInterval(after); result != c.expected {
+			t.Error(c.entry, result, c.expected)
+		}
+	}
+}
+
+func TestValidateMinInterval(t *testing.T) {
+	entry := ScheduleEntry{Cron: "*/30 * * * * *"}
+	if err := entry.ValidateMinInterval(time.Minute); !errors.Is(err, ErrorIntervalTooShort) {
+		t.Error(err)
+	}
+	if err := entry.ValidateMinInterval(30 * time.Second); err != nil {
+		t.Error(err)
+	}
+	if err := entry.ValidateMinInterval(0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSameTrigger(t *testing.T) {
+	anchor := time.Now()
+	entry := ScheduleEntry{Interval: &IntervalTrigger{Every: "PT1H", Anchor: anchor}, Tags: []string{"a"}}
+	if !entry.SameTrigger(ScheduleEntry{Interval: &IntervalTrigger{Every: "PT1H", Anchor: anchor}}) {
+		t.Error("expected same trigger")
+	}
+	if entry.SameTrigger(ScheduleEntry{Interval: &IntervalTrigger{Every: "PT2H", Anchor: anchor}}) {
+		t.Error("expected different trigger")
+	}
+	if entry.SameTrigger(ScheduleEntry{Cron: "0 * * * *"}) {
+		t.Error("expected different trigger")
+	}
+}
+
+func TestRolesContext(t *testing.T) {
+	if roles := GetRoles(context.Background()); roles != nil {
+		t.Error(roles)
+	}
+	if roles := GetRoles(WithRoles(context.Background(), []string{"admin"})); len(roles) != 1 || roles[0] != "admin" {
+		t.Error(roles)
+	}
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/teambition/rrule-go"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrorMissingRruleStart = errors.New("missing DTSTART line")
+var ErrorMissingRrule = errors.New("missing RRULE line")
+
+// MAX_RRULE_ITERATIONS limits the occurrences Next iterates to find the first one after a given time
+const MAX_RRULE_ITERATIONS = 100000
+
+// rruleSeekDistance is the number of rule periods after which Next seeks instead of advancing the kept iterator
+const rruleSeekDistance = 100
+
+// rruleDstWindow is the time before a seek target in which a daylight saving time change widens the seek margin
+const rruleDstWindow = 3 * time.Hour
+
+var ErrorRruleTooManyIterations = fmt.Errorf("DTSTART is too far in the past or COUNT is too large: the next occurrence must be found within %v occurrences", MAX_RRULE_ITERATIONS)
+
+// RruleSchedule is a cron.Schedule of a RFC 5545 recurrence, e.g.
+//
+//	DTSTART;TZID=Europe/Berlin:20240109T090000
+//	RRULE:FREQ=MONTHLY;BYDAY=2TU;UNTIL=20251231T000000Z
+//	EXDATE;TZID=Europe/Berlin:20240813T090000
+//
+// DTSTART is required, so that the occurrences do not depend on the time of parsing; a DTSTART without time zone is local time.
+type RruleSchedule struct {
+	set *rrule.Set
+
+	mux      sync.Mutex
+	iterator func() (time.Time, bool)
+	head     time.Time //next occurrence of iterator that has not been returned yet
+	hasHead  bool
+	floor    time.Time //all consumed occurrences are before or equal to floor
+	steps    int       //iterator steps of the last call of Next
+}
+
+// ParseRrule parses the DTSTART, RRULE, RDATE and EXDATE lines of a recurrence; the lines may be given in any order
+func ParseRrule(text string) (*RruleSchedule, error) {
+	var start string
+	lines := []string{}
+	rules := 0
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name := strings.ToUpper(line)
+		if index := strings.IndexAny(name, ";:"); index >= 0 {
+			name = name[:index]
+		}
+		switch name {
+		case "DTSTART":
+			if start != "" {
+				return nil, errors.New("duplicate DTSTART line")
+			}
+			start = line
+		case "RRULE":
+			rules++
+			lines = append(lines, line)
+		case "RDATE", "EXDATE":
+			lines = append(lines, line)
+		default:
+			return nil, fmt.Errorf("unsupported line %q; expect DTSTART, RRULE, RDATE or EXDATE", line)
+		}
+	}
+	if start == "" {
+		return nil, ErrorMissingRruleStart
+	}
+	if rules == 0 {
+		return nil, ErrorMissingRrule
+	}
+	if rules > 1 {
+		return nil, errors.New("only one RRULE line is supported")
+	}
+	set, err := rrule.StrSliceToRRuleSetInLoc(append([]string{start}, lines...), time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &RruleSchedule{set: set}, nil
+}
+
+// Location returns the time zone of DTSTART
+func (this *RruleSchedule) Location() *time.Location {
+	return this.set.GetDTStart().Location()
+}
+
+// Validate returns ErrorRruleTooManyIterations if Next would have to iterate more than MAX_RRULE_ITERATIONS occurrences
+// to find the first occurrence after now
+func (this *RruleSchedule) Validate(now time.Time) error {
+	if this.set.GetRRule().OrigOptions.Count > MAX_RRULE_ITERATIONS {
+		return ErrorRruleTooManyIterations
+	}
+	iterator := this.seek(now).Iterator()
+	for i := 0; i < MAX_RRULE_ITERATIONS; i++ {
+		next, ok := iterator()
+		if !ok || next.After(now) {
+			return nil
+		}
+	}
+	return ErrorRruleTooManyIterations
+}
+
+// Next returns the first occurrence after t or the zero time if there is none.
+// The iterator is kept between calls, so that ascending calls (like those of the cron loop) do not iterate from DTSTART each time;
+// returns the zero time if no occurrence after t is found within MAX_RRULE_ITERATIONS.
+func (this *RruleSchedule) Next(t time.Time) time.Time {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	period := rrulePeriod(this.set.GetRRule().OrigOptions)
+	if this.iterator == nil || t.Before(this.floor) || (period > 0 && this.seekStart(t).Sub(this.floor) > rruleSeekDistance*period) {
+		this.iterator = this.seek(t).Iterator()
+		this.hasHead = false
+	}
+	this.floor = t
+	for this.steps = 0; this.steps < MAX_RRULE_ITERATIONS; this.steps++ {
+		if !this.hasHead {
+			var ok bool
+			this.head, ok = this.iterator()
+			if !ok {
+				return time.Time{}
+			}
+			this.hasHead = true
+		}
+		if this.head.After(t) {
+			return this.head
+		}
+		this.hasHead = false
+	}
+	this.iterator = nil
+	return time.Time{}
+}
+
+// seek returns a set with the same occurrences from t on, which starts shortly before t if the rule allows it (see seekStart)
+func (this *RruleSchedule) seek(t time.Time) *rrule.Set {
+	start := this.seekStart(t)
+	if start.Equal(this.set.GetDTStart()) {
+		return this.set
+	}
+	options := this.set.GetRRule().OrigOptions
+	options.Dtstart = start
+	rule, err := rrule.NewRRule(options)
+	if err != nil {
+		return this.set
+	}
+	result := &rrule.Set{}
+	result.RRule(rule)
+	for _, date := range this.set.GetRDate() {
+		if !date.Before(start) {
+			result.RDate(date)
+		}
+	}
+	for _, date := range this.set.GetExDate() {
+		result.ExDate(date)
+	}
+	return result
+}
+
+// seekStart moves DTSTART of sub-daily rules without COUNT to the last whole multiple of the rule period before t.
+// The occurrences of such rules form a grid of wall clock times, so the shifted rule has the same occurrences from the new start on.
+func (this *RruleSchedule) seekStart(t time.Time) time.Time {
+	start := this.set.GetDTStart()
+	options := this.set.GetRRule().OrigOptions
+	period := rrulePeriod(options)
+	if period <= 0 || options.Count > 0 {
+		return start
+	}
+	location := start.Location()
+	t = t.In(location)
+	//occurrences after t may have an earlier wall clock time than t if the clock has been turned back shortly before t
+	margin := period
+	_, offset := t.Zone()
+	_, earlierOffset := t.Add(-rruleDstWindow).Zone()
+	if offset != earlierOffset {
+		margin += time.Duration(max(offset-earlierOffset, earlierOffset-offset)) * time.Second
+	}
+	steps := (wallClock(t).Sub(wallClock(start)) - margin) / period
+	for ; steps > 0; steps-- {
+		wall := wallClock(start).Add(steps * period)
+		result := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), start.Nanosecond(), location)
+		if wallClock(result).Equal(wall) { //skipped wall clock times (clock turned forward) would move the grid
+			return result
+		}
+	}
+	return start
+}
+
+// wallClock returns the wall clock time of t as UTC time, so that the difference of two wall clock times ignores daylight saving time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// rrulePeriod returns the distance of two candidate occurrences of sub-daily rules; 0 for daily or longer frequencies
+func rrulePeriod(options rrule.ROption) time.Duration {
+	interval := time.Duration(max(1, options.Interval))
+	switch options.Freq {
+	case rrule.HOURLY:
+		return interval * time.Hour
+	case rrule.MINUTELY:
+		return interval * time.Minute
+	case rrule.SECONDLY:
+		return interval * time.Second
+	default:
+		return 0
+	}
+}
+
+func lcm(a int64, b int64) int64 {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRrule(t *testing.T) {
+	invalid := []string{
+		"RRULE:FREQ=WEEKLY",
+		"DTSTART:20240101T090000Z",
+		"DTSTART:20240101T090000Z\nRRULE:FREQ=FOO",
+		"DTSTART:20240101T090000Z\nDTSTART:20240102T090000Z\nRRULE:FREQ=DAILY",
+		"DTSTART:20240101T090000Z\nRRULE:FREQ=DAILY\nRRULE:FREQ=WEEKLY",
+		"DTSTART:20240101T090000Z\nRRULE:FREQ=DAILY\nSUMMARY:foo",
+		"DTSTART;TZID=Foo/Bar:20240101T090000\nRRULE:FREQ=DAILY",
+	}
+	for i, text := range invalid {
+		_, err := ParseRrule(text)
+		if err == nil {
+			t.Error(i, text)
+		}
+	}
+}
+
+func TestRruleScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(value string) time.Time {
+		result, err := time.ParseInLocation("2006-01-02 15:04", value, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	cases := []struct {
+		rrule    string
+		after    string
+		expected []string
+	}{
+		{
+			rrule:    "DTSTART;TZID=Europe/Berlin:20240109T090000\nRRULE:FREQ=MONTHLY;BYDAY=2TU",
+			after:    "2024-01-01 00:00",
+			expected: []string{"2024-01-09 09:00", "2024-02-13 09:00", "2024-03-12 09:00", "2024-04-09 09:00"},
+		},
+		{
+			//lines in any order; the exdate removes the second occurrence
+			rrule:    "EXDATE;TZID=Europe/Berlin:20240122T080000\nRRULE:FREQ=WEEKLY;INTERVAL=3\nDTSTART;TZID=Europe/Berlin:20240101T080000",
+			after:    "2024-01-01 12:00",
+			expected: []string{"2024-02-12 08:00", "2024-03-04 08:00"},
+		},
+		{
+			rrule:    "DTSTART;TZID=Europe/Berlin:20240101T080000\nRRULE:FREQ=DAILY;COUNT=3",
+			after:    "2023-12-01 00:00",
+			expected: []string{"2024-01-01 08:00", "2024-01-02 08:00", "2024-01-03 08:00", ""},
+		},
+		{
+			rrule:    "DTSTART;TZID=Europe/Berlin:20240101T080000\nRRULE:FREQ=DAILY;UNTIL=20240102T235959Z",
+			after:    "2024-01-01 08:00",
+			expected: []string{"2024-01-02 08:00", ""},
+		},
+	}
+	for i, c := range cases {
+		schedule, err := ParseRrule(c.rrule)
+		if err != nil {
+			t.Error(i, err)
+			continue
+		}
+		next := at(c.after)
+		for j, expected := range c.expected {
+			next = schedule.Next(next)
+			if expected == "" {
+				if !next.IsZero() {
+					t.Error(i, j, next)
+				}
+				break
+			}
+			if !next.Equal(at(expected)) {
+				t.Error(i, j, next, expected)
+			}
+		}
+	}
+}
+
+func TestRruleScheduleNextOutOfOrder(t *testing.T) {
+	schedule, err := ParseRrule("DTSTART:20240101T000000Z\nRRULE:FREQ=HOURLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []int{50, 10, 10, 30, 0} {
+		next := schedule.Next(base.Add(time.Duration(offset)*time.Hour + time.Minute))
+		if !next.Equal(base.Add(time.Duration(offset+1) * time.Hour)) {
+			t.Error(offset, next)
+		}
+	}
+	if location := schedule.Location(); location != time.UTC {
+		t.Error(location)
+	}
+}
+
+func TestRruleScheduleNextOldStart(t *testing.T) {
+	now := time.Now()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//checks that the wall clock time of next is a multiple of period after start
+	onGrid := func(next time.Time, start time.Time, period time.Duration) bool {
+		wall := func(value time.Time) time.Time {
+			return time.Date(value.Year(), value.Month(), value.Day(), value.Hour(), value.Minute(), value.Second(), 0, time.UTC)
+		}
+		return wall(next.In(start.Location())).Sub(wall(start))%period == 0
+	}
+	cases := []struct {
+		rrule   string
+		start   time.Time
+		period  time.Duration
+		maxWait time.Duration
+	}{
+		{"DTSTART:20100101T000000Z\nRRULE:FREQ=MINUTELY", time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), time.Minute, time.Minute},
+		{"DTSTART:20100101T000000Z\nRRULE:FREQ=SECONDLY", time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), time.Second, time.Second},
+		{"DTSTART;TZID=Europe/Berlin:20100101T000030\nRRULE:FREQ=MINUTELY;INTERVAL=7", time.Date(2010, 1, 1, 0, 0, 30, 0, berlin), 7 * time.Minute, 7 * time.Minute},
+		{"DTSTART;TZID=Europe/Berlin:20100101T000000\nRRULE:FREQ=HOURLY;INTERVAL=5;BYDAY=MO,TU", time.Date(2010, 1, 1, 0, 0, 0, 0, berlin), 5 * time.Hour, 7 * 24 * time.Hour},
+	}
+	for _, c := range cases {
+		schedule, err := ParseRrule(c.rrule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = schedule.Validate(now); err != nil {
+			t.Error(c.rrule, err)
+		}
+		for _, after := range []time.Time{now, now.AddDate(-1, 0, 0), now.Add(time.Hour), now.Add(time.Hour + time.Second)} {
+			next := schedule.Next(after)
+			if schedule.steps > int(c.maxWait/c.period)+3 {
+				t.Error("Next iterated too many occurrences", c.rrule, schedule.steps)
+			}
+			if !next.After(after) || next.Sub(after) > c.maxWait || !onGrid(next, c.start, c.period) {
+				t.Error(c.rrule, after, next)
+			}
+		}
+	}
+
+	//rules that can not be shifted are limited by MAX_RRULE_ITERATIONS
+	invalid := []string{
+		"DTSTART:17000101T000000Z\nRRULE:FREQ=DAILY",
+		"DTSTART:20100101T000000Z\nRRULE:FREQ=SECONDLY;COUNT=1000000",
+	}
+	for _, text := range invalid {
+		entry := ScheduleEntry{Rrule: text, ProcessDeploymentId: "d"}
+		if err := entry.Validate(); !errors.Is(err, ErrorRruleTooManyIterations) {
+			t.Error(text, err)
+		}
+		schedule, err := ParseRrule(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := schedule.Next(now)
+		if schedule.steps != MAX_RRULE_ITERATIONS {
+			t.Error("expected iteration limit", text, schedule.steps)
+		}
+		if !next.IsZero() {
+			t.Error(text, next)
+		}
+	}
+}
+
+// seeking must not change the occurrences around daylight saving time changes
+func TestRruleScheduleNextDst(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []string{
+		"DTSTART;TZID=Europe/Berlin:20240301T000030\nRRULE:FREQ=MINUTELY;INTERVAL=7",
+		"DTSTART;TZID=Europe/Berlin:20240301T001500\nRRULE:FREQ=HOURLY;INTERVAL=5",
+	}
+	transitions := []time.Time{time.Date(2024, 3, 31, 2, 0, 0, 0, berlin), time.Date(2024, 10, 27, 2, 0, 0, 0, berlin)}
+	for _, text := range rules {
+		schedule, err := ParseRrule(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, transition := range transitions {
+			for offset := -3 * time.Hour; offset <= 3*time.Hour; offset += 13 * time.Minute {
+				after := transition.Add(offset)
+				expected := schedule.set.After(after, false)
+				if next := schedule.Next(after); !next.Equal(expected) {
+					t.Error(text, after, next, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestScheduleEntryTrigger(t *testing.T) {
+	rrule := "DTSTART:20240101T090000Z\nRRULE:FREQ=WEEKLY;INTERVAL=2"
+	cases := []struct {
+		entry ScheduleEntry
+		err   error
+	}{
+		{ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "d"}, nil},
+		{ScheduleEntry{Rrule: rrule, ProcessDeploymentId: "d"}, nil},
+		{ScheduleEntry{ProcessDeploymentId: "d"}, ErrorMissingTrigger},
+		{ScheduleEntry{Cron: "* * * * *", Rrule: rrule, ProcessDeploymentId: "d"}, ErrorAmbiguousTrigger},
+	}
+	for i, c := range cases {
+		if err := c.entry.Validate(); err != c.err {
+			t.Error(i, err)
+		}
+	}
+	invalid := ScheduleEntry{Rrule: "RRULE:FREQ=WEEKLY", ProcessDeploymentId: "d"}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected missing DTSTART error")
+	}
+	entry := ScheduleEntry{Rrule: rrule, ProcessDeploymentId: "d"}
+	next := entry.GetNextRun(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	if next == nil || !next.Equal(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Error(next)
+	}
+}
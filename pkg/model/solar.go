@@ -0,0 +1,163 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// solar events; dawn and dusk are the begin of the morning and the end of the evening twilight
+const SolarEventSunrise = "sunrise"
+const SolarEventSunset = "sunset"
+const SolarEventCivilDawn = "civil_dawn"
+const SolarEventCivilDusk = "civil_dusk"
+const SolarEventNauticalDawn = "nautical_dawn"
+const SolarEventNauticalDusk = "nautical_dusk"
+
+// MAX_SOLAR_SEARCH_DAYS bounds the search for the next event; near the poles an event may not occur for months
+const MAX_SOLAR_SEARCH_DAYS = 370
+
+// MAX_SOLAR_OFFSET limits the offset to the event
+const MAX_SOLAR_OFFSET = 12 * time.Hour
+
+var ErrorUnknownSolarEvent = errors.New("unknown solar event; expect sunrise, sunset, civil_dawn, civil_dusk, nautical_dawn or nautical_dusk")
+var ErrorInvalidLatitude = errors.New("invalid latitude; expect -90 to 90")
+var ErrorInvalidLongitude = errors.New("invalid longitude; expect -180 to 180")
+var ErrorInvalidSolarOffset = fmt.Errorf("invalid offset; expect a duration like 30m or -1h15m of at most %v", MAX_SOLAR_OFFSET)
+
+// solarElevations are the elevations of the sun center at the events in degrees;
+// sunrise and sunset include the refraction and the radius of the sun
+var solarElevations = map[string]struct {
+	elevation float64
+	rising    bool
+}{
+	SolarEventSunrise:      {-0.833, true},
+	SolarEventSunset:       {-0.833, false},
+	SolarEventCivilDawn:    {-6, true},
+	SolarEventCivilDusk:    {-6, false},
+	SolarEventNauticalDawn: {-12, true},
+	SolarEventNauticalDusk: {-12, false},
+}
+
+// SolarTrigger fires at a solar event at the given position, e.g. 30 minutes after sunset with event "sunset" and offset "30m".
+// The event times are computed locally with the sunrise equation (accurate to about a minute); days without the event
+// (polar day or night) are skipped.
+type SolarTrigger struct {
+	Event     string  `json:"event" bson:"event"`
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+	Offset    string  `json:"offset,omitempty" bson:"offset,omitempty"` //signed duration added to the event time
+}
+
+func (this SolarTrigger) Validate() error {
+	_, err := this.Schedule()
+	return err
+}
+
+// Schedule returns the cron.Schedule of the trigger
+func (this SolarTrigger) Schedule() (*SolarSchedule, error) {
+	event, ok := solarElevations[this.Event]
+	if !ok {
+		return nil, ErrorUnknownSolarEvent
+	}
+	if math.IsNaN(this.Latitude) || this.Latitude < -90 || this.Latitude > 90 {
+		return nil, ErrorInvalidLatitude
+	}
+	if math.IsNaN(this.Longitude) || this.Longitude < -180 || this.Longitude > 180 {
+		return nil, ErrorInvalidLongitude
+	}
+	var offset time.Duration
+	if this.Offset != "" {
+		var err error
+		offset, err = time.ParseDuration(this.Offset)
+		if err != nil || offset > MAX_SOLAR_OFFSET || offset < -MAX_SOLAR_OFFSET {
+			return nil, ErrorInvalidSolarOffset
+		}
+	}
+	return &SolarSchedule{
+		latitude:  this.Latitude,
+		longitude: this.Longitude,
+		elevation: event.elevation,
+		rising:    event.rising,
+		offset:    offset,
+	}, nil
+}
+
+// SolarSchedule is a cron.Schedule of a SolarTrigger; the event time is computed for each day separately
+type SolarSchedule struct {
+	latitude  float64
+	longitude float64
+	elevation float64
+	rising    bool
+	offset    time.Duration
+}
+
+const julianUnixEpoch = 2440587.5
+const julian2000 = 2451545.0
+
+// Next returns the first event (including the offset) after t or the zero time if there is none within MAX_SOLAR_SEARCH_DAYS
+func (this *SolarSchedule) Next(t time.Time) time.Time {
+	//the event of a day may be up to a day before its julian day number (e.g. on the opposite longitude) and the offset shifts it further
+	julianDay := math.Floor(toJulian(t.Add(-this.offset))-julian2000) - 2
+	for i := 0; i < MAX_SOLAR_SEARCH_DAYS; i++ {
+		event, ok := this.event(julianDay + float64(i))
+		if !ok {
+			continue
+		}
+		result := event.Add(this.offset).Truncate(time.Second)
+		if result.After(t) {
+			return result.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
+
+// event computes the event of the given day (days since 2000-01-01 12:00 UTC); ok is false if the sun does not reach the elevation
+func (this *SolarSchedule) event(day float64) (result time.Time, ok bool) {
+	meanNoon := day - this.longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	m := radians(anomaly)
+	center := 1.9148*math.Sin(m) + 0.02*math.Sin(2*m) + 0.0003*math.Sin(3*m)
+	eclipticLongitude := radians(math.Mod(anomaly+center+180+102.9372, 360))
+	transit := julian2000 + meanNoon + 0.0053*math.Sin(m) - 0.0069*math.Sin(2*eclipticLongitude)
+	declination := math.Asin(math.Sin(eclipticLongitude) * math.Sin(radians(23.4397)))
+	latitude := radians(this.latitude)
+	cosHourAngle := (math.Sin(radians(this.elevation)) - math.Sin(latitude)*math.Sin(declination)) / (math.Cos(latitude) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 || math.IsNaN(cosHourAngle) {
+		return result, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+	if this.rising {
+		return fromJulian(transit - hourAngle/360), true
+	}
+	return fromJulian(transit + hourAngle/360), true
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toJulian(t time.Time) float64 {
+	return float64(t.UnixNano())/float64(24*time.Hour) + julianUnixEpoch
+}
+
+func fromJulian(julian float64) time.Time {
+	return time.Unix(0, int64((julian-julianUnixEpoch)*float64(24*time.Hour))).UTC()
+}
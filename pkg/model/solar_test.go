@@ -0,0 +1,141 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolarScheduleNext(t *testing.T) {
+	berlin := SolarTrigger{Latitude: 52.52, Longitude: 13.405}
+	tromso := SolarTrigger{Latitude: 69.65, Longitude: 18.96}
+	sydney := SolarTrigger{Latitude: -33.87, Longitude: 151.21}
+	at := func(value string) time.Time {
+		result, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	with := func(trigger SolarTrigger, event string, offset string) SolarTrigger {
+		trigger.Event = event
+		trigger.Offset = offset
+		return trigger
+	}
+	//expected times in UTC, rounded to the minute; a date only checks the day of the event
+	cases := []struct {
+		trigger  SolarTrigger
+		after    string
+		expected string
+	}{
+		{with(berlin, SolarEventSunrise, ""), "2024-06-21 00:00", "2024-06-21 02:43"},
+		{with(berlin, SolarEventSunset, ""), "2024-06-21 00:00", "2024-06-21 19:33"},
+		{with(berlin, SolarEventCivilDusk, ""), "2024-06-21 00:00", "2024-06-21 20:23"},
+		{with(berlin, SolarEventSunset, "30m"), "2024-06-21 00:00", "2024-06-21 20:03"},
+		{with(berlin, SolarEventSunset, "30m"), "2024-06-21 19:50", "2024-06-21 20:03"}, //after sunset but before the offset
+		{with(berlin, SolarEventSunrise, "-1h"), "2024-06-21 02:00", "2024-06-22 01:43"},
+		{with(berlin, SolarEventSunrise, ""), "2024-12-21 00:00", "2024-12-21 07:16"},
+		{with(sydney, SolarEventSunrise, ""), "2024-06-21 00:00", "2024-06-21 21:00"}, //local morning of the next day
+		{with(tromso, SolarEventNauticalDawn, ""), "2024-12-01 00:00", "2024-12-01"},  //twilight during polar night
+	}
+	for i, c := range cases {
+		schedule, err := c.trigger.Schedule()
+		if err != nil {
+			t.Error(i, err)
+			continue
+		}
+		next := schedule.Next(at(c.after))
+		if len(c.expected) == len("2006-01-02") {
+			if next.Format("2006-01-02") != c.expected {
+				t.Error(i, next, c.expected)
+			}
+			continue
+		}
+		if diff := next.Sub(at(c.expected)); diff < -time.Minute || diff > time.Minute {
+			t.Error(i, next, c.expected)
+		}
+	}
+}
+
+func TestSolarSchedulePolar(t *testing.T) {
+	tromso := SolarTrigger{Latitude: 69.65, Longitude: 18.96}
+	cases := []struct {
+		event    string
+		after    time.Time
+		earliest time.Time
+		latest   time.Time
+	}{
+		{SolarEventSunset, time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC)},  //midnight sun
+		{SolarEventSunrise, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}, //polar night
+	}
+	for i, c := range cases {
+		tromso.Event = c.event
+		schedule, err := tromso.Schedule()
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := schedule.Next(c.after)
+		if next.Before(c.earliest) || next.After(c.latest) {
+			t.Error(i, next)
+		}
+	}
+}
+
+func TestSolarScheduleDaily(t *testing.T) {
+	schedule, err := SolarTrigger{Event: SolarEventSunset, Latitude: 52.52, Longitude: 13.405, Offset: "30m"}.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 60; i++ {
+		previous := next
+		next = schedule.Next(previous)
+		if i > 0 && (next.Sub(previous) < 23*time.Hour || next.Sub(previous) > 25*time.Hour) {
+			t.Error(i, previous, next)
+		}
+	}
+}
+
+func TestSolarTriggerValidate(t *testing.T) {
+	cases := []struct {
+		trigger SolarTrigger
+		valid   bool
+	}{
+		{SolarTrigger{Event: SolarEventSunset, Latitude: 52.5, Longitude: 13.4, Offset: "30m"}, true},
+		{SolarTrigger{Event: SolarEventNauticalDawn, Latitude: -90, Longitude: 180, Offset: "-12h"}, true},
+		{SolarTrigger{Event: "noon", Latitude: 52.5, Longitude: 13.4}, false},
+		{SolarTrigger{Event: SolarEventSunset, Latitude: 91, Longitude: 13.4}, false},
+		{SolarTrigger{Event: SolarEventSunset, Latitude: 52.5, Longitude: -181}, false},
+		{SolarTrigger{Event: SolarEventSunset, Latitude: 52.5, Longitude: 13.4, Offset: "13h"}, false},
+		{SolarTrigger{Event: SolarEventSunset, Latitude: 52.5, Longitude: 13.4, Offset: "foo"}, false},
+	}
+	for i, c := range cases {
+		err := c.trigger.Validate()
+		if (err == nil) != c.valid {
+			t.Error(i, c.trigger, err)
+		}
+	}
+	entry := ScheduleEntry{Cron: "* * * * *", Solar: &SolarTrigger{Event: SolarEventSunset}, ProcessDeploymentId: "d"}
+	if err := entry.Validate(); err != ErrorAmbiguousTrigger {
+		t.Error(err)
+	}
+	entry = ScheduleEntry{Solar: &SolarTrigger{Event: SolarEventSunset, Latitude: 52.5, Longitude: 13.4}, ProcessDeploymentId: "d"}
+	if err := entry.Validate(); err != nil {
+		t.Error(err)
+	}
+}
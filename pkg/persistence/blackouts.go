@@ -0,0 +1,67 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package persistence
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (this *Persistence) blackoutCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoBlackoutCollection)
+}
+
+func (this *Persistence) ListBlackoutWindows(ctx context.Context) (result []model.BlackoutWindow, err error) {
+	ctx, done := this.observe(ctx, "list_blackout_windows")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	cursor, err := this.blackoutCollection().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	result = []model.BlackoutWindow{}
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+func (this *Persistence) SetBlackoutWindow(ctx context.Context, window model.BlackoutWindow) error {
+	ctx, done := this.observe(ctx, "set_blackout_window")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.blackoutCollection().ReplaceOne(ctx, bson.M{"id": window.Id}, window, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (this *Persistence) RemoveBlackoutWindow(ctx context.Context, id string) error {
+	ctx, done := this.observe(ctx, "remove_blackout_window")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result, err := this.blackoutCollection().DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return model.ErrorNotFound
+	}
+	return nil
+}
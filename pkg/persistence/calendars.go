@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package persistence
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (this *Persistence) calendarCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoCalendarCollection)
+}
+
+func (this *Persistence) ListCalendars(ctx context.Context, user string) (result []model.Calendar, err error) {
+	ctx, done := this.observe(ctx, "list_calendars")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	cursor, err := this.calendarCollection().Find(ctx, bson.M{"user": user}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	result = []model.Calendar{}
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+func (this *Persistence) GetCalendar(ctx context.Context, name string, user string) (result model.Calendar, err error) {
+	ctx, done := this.observe(ctx, "get_calendar")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	err = this.calendarCollection().FindOne(ctx, bson.M{"user": user, "name": name}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, model.ErrorNotFound
+	}
+	return result, err
+}
+
+func (this *Persistence) SetCalendar(ctx context.Context, calendar model.Calendar) error {
+	ctx, done := this.observe(ctx, "set_calendar")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.calendarCollection().ReplaceOne(ctx, bson.M{"user": calendar.User, "name": calendar.Name}, calendar, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (this *Persistence) RemoveCalendar(ctx context.Context, name string, user string) error {
+	ctx, done := this.observe(ctx, "remove_calendar")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result, err := this.calendarCollection().DeleteOne(ctx, bson.M{"user": user, "name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return model.ErrorNotFound
+	}
+	return nil
+}
+
+// CountCalendarReferences counts the schedules of the user that reference the calendar as exclusion or inclusion
+func (this *Persistence) CountCalendarReferences(ctx context.Context, name string, user string) (int64, error) {
+	ctx, done := this.observe(ctx, "count_calendar_references")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	return this.collection().CountDocuments(ctx, bson.M{
+		"user": user,
+		"$or":  bson.A{bson.M{"exclude_calendars": name}, bson.M{"include_calendars": name}},
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package persistence
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+func (this *Persistence) executionCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoExecutionCollection)
+}
+
+func (this *Persistence) AddExecution(ctx context.Context, execution model.Execution) error {
+	ctx, done := this.observe(ctx, "add_execution")
+	defer done()
+	if execution.ExpiresAt.IsZero() {
+		execution.ExpiresAt = execution.FinishedAt.Add(this.executionRetention)
+	}
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.executionCollection().InsertOne(ctx, execution)
+	return err
+}
+
+// GetLastExecution returns the latest execution of the schedule; if statuses are given, only executions with one of these statuses are considered
+func (this *Persistence) GetLastExecution(ctx context.Context, scheduleId string, user string, statuses ...string) (result model.Execution, err error) {
+	ctx, done := this.observe(ctx, "get_last_execution")
+	defer done()
+	filter := bson.M{"user": user, "schedule_id": scheduleId}
+	if len(statuses) > 0 {
+		filter["status"] = bson.M{"$in": statuses}
+	}
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	err = this.executionCollection().FindOne(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "started_at", Value: -1}})).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, model.ErrorNotFound
+	}
+	return result, err
+}
+
+// CountExecutions counts the executions of the schedule with the given status, started after since
+func (this *Persistence) CountExecutions(ctx context.Context, scheduleId string, user string, status string, since time.Time) (int64, error) {
+	ctx, done := this.observe(ctx, "count_executions")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	return this.executionCollection().CountDocuments(ctx, bson.M{
+		"user":        user,
+		"schedule_id": scheduleId,
+		"status":      status,
+		"started_at":  bson.M{"$gt": since},
+	})
+}
+
+// RemoveExecutions deletes the execution history of the schedule
+func (this *Persistence) RemoveExecutions(ctx context.Context, scheduleId string, user string) error {
+	ctx, done := this.observe(ctx, "remove_executions")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.executionCollection().DeleteMany(ctx, bson.M{"user": user, "schedule_id": scheduleId})
+	return err
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+)
+
+// Migration changes the stored data or the collection setup from one schema version to the next.
+// Migrations are applied in order of their Version and each one is applied exactly once per database.
+// New migrations must be appended to the migrations list with the next free version number;
+// already released migrations must never be changed.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, this *Persistence) error
+}
+
+type MigrationRecord struct {
+	Version     int       `json:"version" bson:"version"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create schedule indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.collection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "id", Value: 1}},
+					Options: options.Index().SetName("id_unique").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "id", Value: 1}},
+					Options: options.Index().SetName("user_id"),
+				},
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "created_by", Value: 1}},
+					Options: options.Index().SetName("user_created_by"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "initialize schedule versions",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.collection().UpdateMany(ctx, bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "create execution history indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.executionCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "schedule_id", Value: 1}, {Key: "started_at", Value: -1}},
+					Options: options.Index().SetName("user_schedule_started"),
+				},
+				{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "initialize created_at and create list indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			cursor, err := this.collection().Find(ctx, bson.M{"created_at": bson.M{"$exists": false}}, options.Find().SetProjection(bson.M{"_id": 1}))
+			if err != nil {
+				return err
+			}
+			for cursor.Next(ctx) {
+				doc := struct {
+					Id primitive.ObjectID `bson:"_id"`
+				}{}
+				err = cursor.Decode(&doc)
+				if err != nil {
+					return err
+				}
+				_, err = this.collection().UpdateByID(ctx, doc.Id, bson.M{"$set": bson.M{"created_at": doc.Id.Timestamp()}})
+				if err != nil {
+					return err
+				}
+			}
+			err = cursor.Err()
+			if err != nil {
+				return err
+			}
+			_, err = this.collection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "process_deployment_id", Value: 1}},
+					Options: options.Index().SetName("user_process_deployment_id"),
+				},
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "tags", Value: 1}},
+					Options: options.Index().SetName("user_tags"),
+				},
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "created_at", Value: 1}, {Key: "id", Value: 1}},
+					Options: options.Index().SetName("user_created_at"),
+				},
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "next_run", Value: 1}, {Key: "id", Value: 1}},
+					Options: options.Index().SetName("user_next_run"),
+				},
+				{
+					Keys:    bson.D{{Key: "user", Value: 1}, {Key: "process_alias", Value: 1}, {Key: "id", Value: 1}},
+					Options: options.Index().SetName("user_process_alias"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "create blackout window indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.blackoutCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "id", Value: 1}},
+				Options: options.Index().SetName("id_unique").SetUnique(true),
+			})
+			return err
+		},
+	},
+	{
+		Version:     6,
+		Description: "create calendar indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.calendarCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user", Value: 1}, {Key: "name", Value: 1}},
+				Options: options.Index().SetName("user_name_unique").SetUnique(true),
+			})
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "create quota override indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.quotaCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user", Value: 1}},
+				Options: options.Index().SetName("user_unique").SetUnique(true),
+			})
+			return err
+		},
+	},
+	{
+		Version:     8,
+		Description: "create quota lock indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.quotaLockCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user", Value: 1}},
+				Options: options.Index().SetName("user_unique").SetUnique(true),
+			})
+			return err
+		},
+	},
+	{
+		Version:     9,
+		Description: "create pause record indexes",
+		Up: func(ctx context.Context, this *Persistence) error {
+			_, err := this.pauseCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user", Value: 1}, {Key: "key", Value: 1}},
+				Options: options.Index().SetName("user_key_unique").SetUnique(true),
+			})
+			return err
+		},
+	},
+}
+
+func (this *Persistence) metadataCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoMetadataCollection)
+}
+
+// Migrate applies all migrations that are not yet recorded in the metadata collection.
+// A unique index on the version field of the metadata collection prevents two instances from recording the same migration;
+// migrations should therefore be idempotent, so that a concurrently running instance may repeat them without harm.
+func (this *Persistence) Migrate() error {
+	ctx, cancel := getTimeoutContext(context.Background())
+	defer cancel()
+	_, err := this.metadataCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetName("version_unique").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	current, err := this.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		slog.Info("apply migration", "version", migration.Version, "description", migration.Description)
+		migrationCtx, cancel := context.WithTimeout(context.Background(), MIGRATION_TIMEOUT)
+		err = migration.Up(migrationCtx, this)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("migration %v (%v) failed: %w", migration.Version, migration.Description, err)
+		}
+		err = this.recordMigration(migration)
+		if mongo.IsDuplicateKeyError(err) {
+			slog.Info("migration was recorded by another instance", "version", migration.Version)
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Persistence) recordMigration(migration Migration) error {
+	ctx, cancel := getTimeoutContext(context.Background())
+	defer cancel()
+	_, err := this.metadataCollection().InsertOne(ctx, MigrationRecord{
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppliedAt:   time.Now(),
+	})
+	return err
+}
+
+// SchemaVersion returns the highest applied migration version; 0 if no migration has been applied
+func (this *Persistence) SchemaVersion() (version int, err error) {
+	ctx, cancel := getTimeoutContext(context.Background())
+	defer cancel()
+	record := MigrationRecord{}
+	err = this.metadataCollection().FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return record.Version, nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package persistence
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (this *Persistence) pauseCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoPauseCollection)
+}
+
+func (this *Persistence) ListPauses(ctx context.Context, user string) (result []model.PauseRecord, err error) {
+	return this.findPauses(ctx, "list_pauses", bson.M{"user": user})
+}
+
+// GetAllPauses returns the pause records of all users
+func (this *Persistence) GetAllPauses(ctx context.Context) (result []model.PauseRecord, err error) {
+	return this.findPauses(ctx, "get_all_pauses", bson.M{})
+}
+
+func (this *Persistence) findPauses(ctx context.Context, op string, filter bson.M) (result []model.PauseRecord, err error) {
+	ctx, done := this.observe(ctx, op)
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	cursor, err := this.pauseCollection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "paused_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	result = []model.PauseRecord{}
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+// AddPause stores the record if the user has no record with the same key; an existing record keeps its paused_at
+func (this *Persistence) AddPause(ctx context.Context, record model.PauseRecord) error {
+	ctx, done := this.observe(ctx, "add_pause")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.pauseCollection().UpdateOne(ctx, bson.M{"user": record.User, "key": record.Key}, bson.M{"$setOnInsert": record}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		//concurrent upsert of the same record
+		return nil
+	}
+	return err
+}
+
+func (this *Persistence) RemovePause(ctx context.Context, key string, user string) error {
+	ctx, done := this.observe(ctx, "remove_pause")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result, err := this.pauseCollection().DeleteOne(ctx, bson.M{"user": user, "key": key})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return model.ErrorNotFound
+	}
+	return nil
+}
@@ -18,36 +18,59 @@ package persistence
 
 import (
 	"context"
+	"fmt"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"regexp"
 	"sync"
 	"time"
 )
 
 const TIMEOUT = 10 * time.Second
+const MIGRATION_TIMEOUT = 5 * time.Minute
+const DEFAULT_EXECUTION_RETENTION = 7 * 24 * time.Hour
 
 type Persistence struct {
-	config configuration.Config
-	client *mongo.Client
+	config             configuration.Config
+	client             *mongo.Client
+	executionRetention time.Duration
+	metrics            *metrics.Metrics
+	tracing            *tracing.Tracing
 }
 
-func New(ctx context.Context, wg *sync.WaitGroup, config configuration.Config) (scheduler.Persistence, error) {
+// New connects to mongodb and applies pending migrations; m and t may be nil
+func New(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, m *metrics.Metrics, t *tracing.Tracing) (scheduler.Persistence, error) {
 	var parentCtx context.Context
 	if ctx != nil {
 		parentCtx = ctx
 	} else {
 		parentCtx = context.Background()
 	}
-	timeout, _ := context.WithTimeout(parentCtx, TIMEOUT)
+	executionRetention, err := getExecutionRetention(config)
+	if err != nil {
+		return nil, err
+	}
+	timeout, cancel := context.WithTimeout(parentCtx, TIMEOUT)
+	defer cancel()
 	client, err := mongo.Connect(timeout, options.Client().ApplyURI(config.MongoUrl))
 	if err != nil {
 		return nil, err
 	}
-	result := &Persistence{config: config, client: client}
+	db := &Persistence{config: config, client: client, executionRetention: executionRetention, metrics: m, tracing: t}
+	err = db.Migrate()
+	if err != nil {
+		db.Disconnect()
+		return nil, err
+	}
 
 	if ctx != nil {
 		if wg != nil {
@@ -55,27 +78,37 @@ func New(ctx context.Context, wg *sync.WaitGroup, config configuration.Config) (
 		}
 		go func() {
 			<-ctx.Done()
-			result.Disconnect()
+			db.Disconnect()
 			if wg != nil {
 				wg.Done()
 			}
 		}()
 	}
-	return result, err
+	return db, err
 }
 
 func (this *Persistence) Disconnect() {
-	ctx, _ := getTimeoutContext()
+	ctx, cancel := getTimeoutContext(context.Background())
+	defer cancel()
 	this.client.Disconnect(ctx)
 	return
 }
 
+func (this *Persistence) Ping(ctx context.Context) error {
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	return this.client.Ping(ctx, readpref.Primary())
+}
+
 func (this *Persistence) collection() *mongo.Collection {
 	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoCollection)
 }
 
-func (this *Persistence) GetAll() (result []model.ScheduleEntry, err error) {
-	ctx, _ := getTimeoutContext()
+func (this *Persistence) GetAll(ctx context.Context) (result []model.ScheduleEntry, err error) {
+	ctx, done := this.observe(ctx, "get_all")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
 	cursor, err := this.collection().Find(ctx, bson.M{})
 	if err != nil {
 		return nil, err
@@ -92,14 +125,45 @@ func (this *Persistence) GetAll() (result []model.ScheduleEntry, err error) {
 	return
 }
 
-func (this *Persistence) Set(entry model.ScheduleEntry) error {
-	ctx, _ := getTimeoutContext()
+func (this *Persistence) Set(ctx context.Context, entry model.ScheduleEntry) error {
+	ctx, done := this.observe(ctx, "set")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
 	_, err := this.collection().ReplaceOne(ctx, bson.M{"user": entry.User, "id": entry.Id}, entry, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return model.ErrorDuplicateId
+	}
 	return err
 }
 
-func (this *Persistence) Get(id string, user string) (result model.ScheduleEntry, err error) {
-	ctx, _ := getTimeoutContext()
+// Update replaces the stored entry only if its version still matches expectedVersion and its paused state matches entry.Paused.
+// The check and the replacement happen in a single atomic ReplaceOne call; a concurrent pause or resume, which does not
+// change the version, is reported as model.ErrorVersionConflict instead of being overwritten.
+func (this *Persistence) Update(ctx context.Context, entry model.ScheduleEntry, expectedVersion int64) error {
+	ctx, done := this.observe(ctx, "update")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result, err := this.collection().ReplaceOne(ctx, bson.M{"user": entry.User, "id": entry.Id, "version": expectedVersion, "paused": bson.M{"$ne": !entry.Paused}}, entry)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		_, err = this.Get(ctx, entry.Id, entry.User)
+		if err != nil {
+			return err
+		}
+		return model.ErrorVersionConflict
+	}
+	return nil
+}
+
+func (this *Persistence) Get(ctx context.Context, id string, user string) (result model.ScheduleEntry, err error) {
+	ctx, done := this.observe(ctx, "get")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
 	err = this.collection().FindOne(ctx, bson.M{"user": user, "id": id}).Decode(&result)
 	if err == mongo.ErrNoDocuments {
 		return result, model.ErrorNotFound
@@ -107,27 +171,132 @@ func (this *Persistence) Get(id string, user string) (result model.ScheduleEntry
 	return result, err
 }
 
-func (this *Persistence) Remove(id string, user string) (err error) {
-	ctx, _ := getTimeoutContext()
+// IdExists returns true if any user has an entry with the id; ids are unique across users
+func (this *Persistence) IdExists(ctx context.Context, id string) (bool, error) {
+	ctx, done := this.observe(ctx, "id_exists")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	count, err := this.collection().CountDocuments(ctx, bson.M{"id": id}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// Remove deletes the entry; its execution history is kept until RemoveExecutions is called
+func (this *Persistence) Remove(ctx context.Context, id string, user string) (err error) {
+	ctx, done := this.observe(ctx, "remove")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
 	_, err = this.collection().DeleteOne(ctx, bson.M{"user": user, "id": id})
-	return
+	return err
+}
+
+// SetNextRun stores the next planned run of the entry; the entry version is not changed because next_run is derived from the schedule
+func (this *Persistence) SetNextRun(ctx context.Context, id string, user string, next *time.Time) error {
+	ctx, done := this.observe(ctx, "set_next_run")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.collection().UpdateOne(ctx, bson.M{"user": user, "id": id}, bson.M{"$set": bson.M{"next_run": next}})
+	return err
+}
+
+// SetFired stores the planned time of a firing and the next planned run; like SetNextRun, the entry version is not changed
+func (this *Persistence) SetFired(ctx context.Context, id string, user string, firedAt time.Time, next *time.Time) error {
+	ctx, done := this.observe(ctx, "set_fired")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.collection().UpdateOne(ctx, bson.M{"user": user, "id": id}, bson.M{"$set": bson.M{"next_run": next, "last_fired_at": firedAt}})
+	return err
+}
+
+// SetPaused stores the paused state and the next planned run of the entry; the entry version is not changed,
+// so that pausing and resuming do not invalidate the If-Match versions of concurrent editors
+func (this *Persistence) SetPaused(ctx context.Context, id string, user string, paused bool, next *time.Time) error {
+	ctx, done := this.observe(ctx, "set_paused")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result, err := this.collection().UpdateOne(ctx, bson.M{"user": user, "id": id}, bson.M{"$set": bson.M{"paused": paused, "next_run": next}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return model.ErrorNotFound
+	}
+	return nil
 }
 
-func (this *Persistence) List(user string, createdBy *string) (result []model.ScheduleEntry, err error) {
+func (this *Persistence) List(ctx context.Context, user string, listOptions model.ListOptions) (result []model.ScheduleEntry, total int64, err error) {
+	ctx, done := this.observe(ctx, "list")
+	defer done()
 	filter := bson.M{"user": user}
-	if createdBy != nil && *createdBy != "" {
-		filter["created_by"] = *createdBy
+	if listOptions.CreatedBy != nil && *listOptions.CreatedBy != "" {
+		filter["created_by"] = *listOptions.CreatedBy
+	}
+	if listOptions.ProcessDeploymentId != nil && *listOptions.ProcessDeploymentId != "" {
+		filter["process_deployment_id"] = *listOptions.ProcessDeploymentId
+	}
+	if listOptions.Disabled != nil {
+		if *listOptions.Disabled {
+			filter["disabled"] = true
+		} else {
+			filter["disabled"] = bson.M{"$ne": true}
+		}
+	}
+	if listOptions.Paused != nil {
+		if *listOptions.Paused {
+			filter["paused"] = true
+		} else {
+			filter["paused"] = bson.M{"$ne": true}
+		}
 	}
-	ctx, _ := getTimeoutContext()
-	cursor, err := this.collection().Find(ctx, filter)
+	if listOptions.AliasSearch != "" {
+		filter["process_alias"] = bson.M{"$regex": regexp.QuoteMeta(listOptions.AliasSearch), "$options": "i"}
+	}
+	if len(listOptions.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": listOptions.Tags}
+	}
+
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	total, err = this.collection().CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, total, err
+	}
+
+	direction := 1
+	if listOptions.SortDesc {
+		direction = -1
+	}
+	sort := bson.D{}
+	switch listOptions.SortBy {
+	case model.SortByAlias:
+		sort = append(sort, bson.E{Key: "process_alias", Value: direction})
+	case model.SortByCreatedAt:
+		sort = append(sort, bson.E{Key: "created_at", Value: direction})
+	case model.SortByNextRun:
+		sort = append(sort, bson.E{Key: "next_run", Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "id", Value: direction})
+	findOptions := options.Find().SetSort(sort)
+	if listOptions.Limit > 0 {
+		findOptions.SetLimit(listOptions.Limit)
+	}
+	if listOptions.Offset > 0 {
+		findOptions.SetSkip(listOptions.Offset)
+	}
+
+	cursor, err := this.collection().Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, total, err
 	}
 	for cursor.Next(context.Background()) {
 		entry := model.ScheduleEntry{}
 		err = cursor.Decode(&entry)
 		if err != nil {
-			return nil, err
+			return nil, total, err
 		}
 		result = append(result, entry)
 	}
@@ -135,6 +304,28 @@ func (this *Persistence) List(user string, createdBy *string) (result []model.Sc
 	return
 }
 
-func getTimeoutContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), TIMEOUT)
+func getExecutionRetention(config configuration.Config) (time.Duration, error) {
+	if config.ExecutionHistoryRetention == "" {
+		return DEFAULT_EXECUTION_RETENTION, nil
+	}
+	result, err := time.ParseDuration(config.ExecutionHistoryRetention)
+	if err != nil {
+		return result, fmt.Errorf("invalid execution_history_retention: %w", err)
+	}
+	return result, nil
+}
+
+// getTimeoutContext keeps the values (e.g. the trace span) of parent but not its cancellation
+func getTimeoutContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(parent), TIMEOUT)
+}
+
+// observe starts a span and a latency measurement of the named operation; the returned function ends both
+func (this *Persistence) observe(ctx context.Context, operation string) (context.Context, func()) {
+	stop := this.metrics.ObservePersistence(operation)
+	ctx, span := this.tracing.Start(ctx, "persistence."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.String("db.system", "mongodb")))
+	return ctx, func() {
+		span.End()
+		stop()
+	}
 }
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package persistence
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+func (this *Persistence) quotaCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoQuotaCollection)
+}
+
+func (this *Persistence) quotaLockCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.MongoQuotaLockCollection)
+}
+
+type quotaLock struct {
+	User  string    `bson:"user"`
+	Token string    `bson:"token"`
+	Until time.Time `bson:"until"`
+}
+
+// LockQuota acquires or extends the quota lock of the user for token until the given time;
+// returns false if the lock is held by another token and has not yet expired.
+// The unique user index lets only one of concurrent upserts of different tokens create the lock document.
+func (this *Persistence) LockQuota(ctx context.Context, user string, token string, until time.Time) (acquired bool, err error) {
+	ctx, done := this.observe(ctx, "lock_quota")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	filter := bson.M{"user": user, "$or": []bson.M{{"token": token}, {"until": bson.M{"$lt": time.Now()}}}}
+	_, err = this.quotaLockCollection().ReplaceOne(ctx, filter, quotaLock{User: user, Token: token, Until: until}, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnlockQuota releases the quota lock of the user if it is still held by token
+func (this *Persistence) UnlockQuota(ctx context.Context, user string, token string) error {
+	ctx, done := this.observe(ctx, "unlock_quota")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.quotaLockCollection().DeleteOne(ctx, bson.M{"user": user, "token": token})
+	return err
+}
+
+func (this *Persistence) ListQuotaOverrides(ctx context.Context) (result []model.QuotaOverride, err error) {
+	ctx, done := this.observe(ctx, "list_quota_overrides")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	cursor, err := this.quotaCollection().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "user", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	result = []model.QuotaOverride{}
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+func (this *Persistence) GetQuotaOverride(ctx context.Context, user string) (result model.QuotaOverride, err error) {
+	ctx, done := this.observe(ctx, "get_quota_override")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	err = this.quotaCollection().FindOne(ctx, bson.M{"user": user}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, model.ErrorNotFound
+	}
+	return result, err
+}
+
+func (this *Persistence) SetQuotaOverride(ctx context.Context, override model.QuotaOverride) error {
+	ctx, done := this.observe(ctx, "set_quota_override")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	_, err := this.quotaCollection().ReplaceOne(ctx, bson.M{"user": override.User}, override, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (this *Persistence) RemoveQuotaOverride(ctx context.Context, user string) error {
+	ctx, done := this.observe(ctx, "remove_quota_override")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result, err := this.quotaCollection().DeleteOne(ctx, bson.M{"user": user})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return model.ErrorNotFound
+	}
+	return nil
+}
+
+// CountSchedules counts all schedules, the enabled schedules and the schedules of the process deployment of the user
+func (this *Persistence) CountSchedules(ctx context.Context, user string, deploymentId string) (result model.ScheduleCounts, err error) {
+	ctx, done := this.observe(ctx, "count_schedules")
+	defer done()
+	ctx, cancel := getTimeoutContext(ctx)
+	defer cancel()
+	result.Total, err = this.collection().CountDocuments(ctx, bson.M{"user": user})
+	if err != nil {
+		return result, err
+	}
+	result.Enabled, err = this.collection().CountDocuments(ctx, bson.M{"user": user, "disabled": bson.M{"$ne": true}})
+	if err != nil {
+		return result, err
+	}
+	result.Deployment, err = this.collection().CountDocuments(ctx, bson.M{"user": user, "process_deployment_id": deploymentId})
+	return result, err
+}
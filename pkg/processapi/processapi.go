@@ -19,53 +19,89 @@ package processapi
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"runtime/debug"
 	"time"
 )
 
 type ProcessApi struct {
-	config configuration.Config
+	config  configuration.Config
+	tracing *tracing.Tracing
 }
 
-func New(config configuration.Config) (result *ProcessApi) {
-	return &ProcessApi{config: config}
+// New creates the process engine client; t may be nil
+func New(config configuration.Config, t *tracing.Tracing) (result *ProcessApi) {
+	return &ProcessApi{config: config, tracing: t}
 }
 
-func (this ProcessApi) Execute(entry model.ScheduleEntry) {
+// Execute starts the process deployment of entry; the trace context of ctx is propagated as W3C traceparent header
+func (this ProcessApi) Execute(ctx context.Context, entry model.ScheduleEntry) (statusCode int, err error) {
 	endpoint := this.config.ProcessEndpoint + "/deployment/" + url.PathEscape(entry.ProcessDeploymentId) + "/start"
-	req, err := http.NewRequest("GET", endpoint, nil)
+	ctx, span := this.tracing.Start(ctx, "processapi.execute", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", http.MethodGet),
+		attribute.String("url.full", endpoint),
+		attribute.String("schedule.process_deployment_id", entry.ProcessDeploymentId),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+		tracing.End(span, err)
+	}()
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		log.Println("ERROR: decrypt new request", err)
-		return
+		slog.ErrorContext(ctx, "unable to create process start request", "error", err)
+		return statusCode, err
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	req.WithContext(ctx)
+	tracing.Propagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	err = SetAuthToken(req, entry.User)
 	if err != nil {
-		log.Println("ERROR: SetAuthToken:", err)
-		debug.PrintStack()
-		return
+		slog.ErrorContext(ctx, "unable to set auth token", "error", err)
+		return statusCode, err
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log.Println("ERROR: decrypt request do", err)
-		return
+		slog.ErrorContext(ctx, "unable to send process start request", "endpoint", endpoint, "error", err)
+		return statusCode, err
 	}
 
 	defer resp.Body.Close()
 	temp, _ := io.ReadAll(resp.Body) //ensure empty stream
 	if resp.StatusCode != http.StatusOK {
 		err = errors.New("unexpected response code from " + endpoint)
-		log.Println("ERROR: ", err, resp.StatusCode, string(temp))
-		return
+		slog.ErrorContext(ctx, "unable to start process", "endpoint", endpoint, "status_code", resp.StatusCode, "response", string(temp))
+		return resp.StatusCode, err
 	}
-	return
+	return resp.StatusCode, nil
+}
+
+// Ping checks if the process endpoint is reachable; every response that is not a server error counts as reachable
+func (this ProcessApi) Ping(ctx context.Context) error {
+	if this.config.ProcessEndpoint == "" {
+		return errors.New("process_endpoint is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, this.config.ProcessEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("process endpoint responded with %v", resp.StatusCode)
+	}
+	return nil
 }
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func (this *Scheduler) ListBlackoutWindows(ctx context.Context) (result []model.BlackoutWindow, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.list_blackout_windows", "", "")
+	defer func() { end(err) }()
+	result, err = this.persistence.ListBlackoutWindows(ctx)
+	return result, err, getErrCode(err)
+}
+
+func (this *Scheduler) AddBlackoutWindow(ctx context.Context, window model.BlackoutWindow, user string) (result model.BlackoutWindow, err error, code int) {
+	window.Id = uuid.New().String()
+	ctx, end := this.startSpan(ctx, "scheduler.add_blackout_window", window.Id, user)
+	defer func() { end(err) }()
+	window.CreatedBy = user
+	window.CreatedAt = time.Now()
+	return this.setBlackoutWindow(ctx, window)
+}
+
+// UpdateBlackoutWindow replaces the window with the same id; created_by and created_at are kept
+func (this *Scheduler) UpdateBlackoutWindow(ctx context.Context, window model.BlackoutWindow, user string) (result model.BlackoutWindow, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.update_blackout_window", window.Id, user)
+	defer func() { end(err) }()
+	windows, err := this.persistence.ListBlackoutWindows(ctx)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	for _, old := range windows {
+		if old.Id == window.Id {
+			window.CreatedBy = old.CreatedBy
+			window.CreatedAt = old.CreatedAt
+			return this.setBlackoutWindow(ctx, window)
+		}
+	}
+	return result, model.ErrorNotFound, getErrCode(model.ErrorNotFound)
+}
+
+func (this *Scheduler) setBlackoutWindow(ctx context.Context, window model.BlackoutWindow) (result model.BlackoutWindow, err error, code int) {
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = window.Validate()
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	err = this.persistence.SetBlackoutWindow(ctx, window)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	this.reloadBlackoutWindows(ctx)
+	return window, nil, http.StatusOK
+}
+
+func (this *Scheduler) RemoveBlackoutWindow(ctx context.Context, id string, user string) (err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.remove_blackout_window", id, user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return err, getErrCode(err)
+	}
+	err = this.persistence.RemoveBlackoutWindow(ctx, id)
+	if err != nil {
+		return err, getErrCode(err)
+	}
+	this.reloadBlackoutWindows(ctx)
+	return nil, http.StatusOK
+}
+
+// loadBlackoutWindows replaces the cached windows with the persisted ones
+func (this *Scheduler) loadBlackoutWindows(ctx context.Context) error {
+	windows, err := this.persistence.ListBlackoutWindows(ctx)
+	if err != nil {
+		return err
+	}
+	this.blackoutMux.Lock()
+	defer this.blackoutMux.Unlock()
+	this.blackouts = windows
+	return nil
+}
+
+// reloadBlackoutWindows refreshes the cache after a change; on failure the periodic reconciliation retries
+func (this *Scheduler) reloadBlackoutWindows(ctx context.Context) {
+	err := this.loadBlackoutWindows(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "unable to reload blackout windows; retry with next reconciliation", "error", err)
+	}
+}
+
+// activeBlackout returns the active window that ends last
+func (this *Scheduler) activeBlackout(t time.Time) (result model.BlackoutWindow, end time.Time, active bool) {
+	this.blackoutMux.RLock()
+	defer this.blackoutMux.RUnlock()
+	for _, window := range this.blackouts {
+		windowEnd, windowActive := window.ActiveUntil(t)
+		if windowActive && (!active || windowEnd.After(end)) {
+			result, end, active = window, windowEnd, true
+		}
+	}
+	return result, end, active
+}
+
+// suppress records a run that has been suppressed by a blackout window and applies the misfire policy of the entry
+func (this *Scheduler) suppress(ctx context.Context, span trace.Span, entry model.ScheduleEntry, executionId string, window model.BlackoutWindow, windowEnd time.Time, delay time.Duration) {
+	span.SetAttributes(attribute.String("blackout.id", window.Id))
+	this.recordSkipped(ctx, span, entry, executionId, blackoutReason(window), delay)
+	policy := entry.GetMisfirePolicy()
+	slog.DebugContext(ctx, "execution suppressed by blackout window", "blackout_id", window.Id, "blackout_end", windowEnd, "misfire_policy", policy)
+	if policy == model.MisfirePolicyFireOnce {
+		this.scheduleMisfire(entry, windowEnd)
+	}
+}
+
+func blackoutReason(window model.BlackoutWindow) string {
+	return "blackout window " + window.Id
+}
+
+// scheduleMisfire runs entry once at the given time; further misfires of the entry until then are coalesced.
+// the run is dropped if the entry is changed or removed in the meantime.
+func (this *Scheduler) scheduleMisfire(entry model.ScheduleEntry, at time.Time) {
+	this.misfireMux.Lock()
+	defer this.misfireMux.Unlock()
+	if this.stopping.Load() {
+		return
+	}
+	if _, pending := this.misfires[entry.Id]; pending {
+		return
+	}
+	this.misfireWg.Add(1)
+	this.misfires[entry.Id] = time.AfterFunc(time.Until(at), func() {
+		defer this.misfireWg.Done()
+		this.misfireMux.Lock()
+		delete(this.misfires, entry.Id)
+		this.misfireMux.Unlock()
+		if this.stopping.Load() || !this.isRegistered(entry) || !entry.IsScheduled() {
+			return
+		}
+		this.runJob(entry, at)
+	})
+}
+
+// cancelMisfires stops all pending misfire runs
+func (this *Scheduler) cancelMisfires() {
+	this.misfireMux.Lock()
+	defer this.misfireMux.Unlock()
+	for id, timer := range this.misfires {
+		if timer.Stop() {
+			this.misfireWg.Done()
+		}
+		delete(this.misfires, id)
+	}
+}
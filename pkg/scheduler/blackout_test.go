@@ -0,0 +1,148 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBlackoutWindows(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+
+	now := time.Now()
+	inOneHour := now.Add(time.Hour)
+	window := model.BlackoutWindow{}
+
+	t.Run("invalid window", func(t *testing.T) {
+		_, err, code := s.AddBlackoutWindow(ctx, model.BlackoutWindow{Cron: "0 2 * * 0"}, "admin")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+	t.Run("add window", func(t *testing.T) {
+		var err error
+		window, err, _ = s.AddBlackoutWindow(ctx, model.BlackoutWindow{Start: &now, End: &inOneHour}, "admin")
+		if err != nil || window.Id == "" || window.CreatedBy != "admin" {
+			t.Error(err, window)
+		}
+	})
+
+	skip, _, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d"}, "user")
+	t.Run("suppressed run is recorded as skipped", func(t *testing.T) {
+		s.runJob(skip, time.Now())
+		executions := p.executionsOf(skip.Id)
+		if len(executions) != 1 || executions[0].Status != model.ExecutionStatusSkipped || executions[0].Reason == "" {
+			t.Error(executions)
+		}
+		if process.count(skip.Id) != 0 {
+			t.Error("process has been started")
+		}
+	})
+
+	t.Run("update unknown window", func(t *testing.T) {
+		_, err, code := s.UpdateBlackoutWindow(ctx, model.BlackoutWindow{Id: "unknown", Start: &now, End: &inOneHour}, "admin")
+		expectCode(t, err, code, http.StatusNotFound)
+	})
+
+	fireOnce, _, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d", MisfirePolicy: model.MisfirePolicyFireOnce}, "user")
+	t.Run("fire once after window", func(t *testing.T) {
+		soon := time.Now().Add(200 * time.Millisecond)
+		window.End = &soon
+		_, err, _ := s.UpdateBlackoutWindow(ctx, window, "admin")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.runJob(fireOnce, time.Now())
+		s.runJob(fireOnce, time.Now())
+		s.runJob(skip, time.Now())
+		if process.count(fireOnce.Id) != 0 {
+			t.Error("process has been started during the window")
+		}
+		time.Sleep(500 * time.Millisecond)
+		if count := process.count(fireOnce.Id); count != 1 {
+			t.Error("expected one coalesced run", count)
+		}
+		if count := process.count(skip.Id); count != 0 {
+			t.Error("unexpected run with misfire policy skip", count)
+		}
+		executions := p.executionsOf(fireOnce.Id)
+		if len(executions) != 3 || executions[2].Status != model.ExecutionStatusSuccess {
+			t.Error(executions)
+		}
+	})
+
+	t.Run("misfire is dropped if the entry changes", func(t *testing.T) {
+		soon := time.Now().Add(200 * time.Millisecond)
+		window.End = &soon
+		_, err, _ := s.UpdateBlackoutWindow(ctx, window, "admin")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.runJob(fireOnce, time.Now())
+		_, err, _ = s.Patch(ctx, fireOnce.Id, "user", []byte(`{"cron":"30 * * * *"}`), nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+		if count := process.count(fireOnce.Id); count != 1 {
+			t.Error("unexpected run of outdated entry", count)
+		}
+	})
+
+	t.Run("remove window", func(t *testing.T) {
+		err, _ := s.RemoveBlackoutWindow(ctx, window.Id, "admin")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.runJob(skip, time.Now())
+		if count := process.count(skip.Id); count != 1 {
+			t.Error("expected run without window", count)
+		}
+		err, code := s.RemoveBlackoutWindow(ctx, window.Id, "admin")
+		expectCode(t, err, code, http.StatusNotFound)
+	})
+}
+
+func TestStopCancelsMisfires(t *testing.T) {
+	s, _, stop := startTestScheduler(t, "0")
+	ctx := context.Background()
+	now := time.Now()
+	later := now.Add(time.Hour)
+	_, err, _ := s.AddBlackoutWindow(ctx, model.BlackoutWindow{Start: &now, End: &later}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d", MisfirePolicy: model.MisfirePolicyFireOnce}, "user")
+	s.runJob(entry, time.Now())
+	start := time.Now()
+	stop()
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("stop waited for pending misfire")
+	}
+	if s.processes.(*processMock).count(entry.Id) != 0 {
+		t.Error("pending misfire has been executed")
+	}
+}
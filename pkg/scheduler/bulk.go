@@ -0,0 +1,215 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Bulk validates all operations and applies them in order.
+// For atomic requests nothing is applied if any operation is invalid, and already applied operations
+// are rolled back (in persistence and cron registry) if a later operation fails.
+// The execution history of deleted entries is removed after all operations are applied, so that a rollback restores it with the entry.
+func (this *Scheduler) Bulk(ctx context.Context, request model.BulkRequest, user string) (result model.BulkResponse, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.bulk", "", user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = request.Validate()
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	result.Results = make([]model.BulkResult, len(request.Operations))
+	valid := true
+	for i := range request.Operations {
+		op := &request.Operations[i]
+		result.Results[i] = model.BulkResult{Index: i, Op: op.Op, Id: op.Id, Status: http.StatusOK}
+		if err := op.Validate(); err != nil {
+			valid = false
+			result.Results[i].Id = op.Id
+			result.Results[i].Status = http.StatusBadRequest
+			result.Results[i].Error = err.Error()
+		}
+	}
+	if request.Atomic && !valid {
+		markNotApplied(result.Results, -1)
+		return result, nil, http.StatusBadRequest
+	}
+
+	undo := []func() error{}
+	deleted := []int{}
+	for i, op := range request.Operations {
+		if result.Results[i].Status != http.StatusOK {
+			continue
+		}
+		entry, rollback, err, code := this.applyBulkOperation(ctx, op, user)
+		if err != nil {
+			result.Results[i].Status = code
+			result.Results[i].Error = err.Error()
+			if request.Atomic {
+				for j := len(undo) - 1; j >= 0; j-- {
+					if undo[j] == nil {
+						continue
+					}
+					if rollbackErr := undo[j](); rollbackErr != nil {
+						slog.ErrorContext(ctx, "unable to roll back bulk operation", "index", j, "error", rollbackErr)
+						result.Results[j].Error = "rollback failed: " + rollbackErr.Error()
+					}
+				}
+				markNotApplied(result.Results, i)
+				return result, nil, code
+			}
+			continue
+		}
+		undo = append(undo, rollback)
+		result.Results[i].Entry = entry
+		if entry != nil {
+			result.Results[i].Id = entry.Id
+		}
+		if op.Op == model.BulkOpDelete {
+			deleted = append(deleted, i)
+		}
+	}
+	for _, i := range deleted {
+		if err := this.persistence.RemoveExecutions(ctx, request.Operations[i].Id, user); err != nil {
+			slog.ErrorContext(ctx, "unable to remove execution history of deleted entry", "index", i, "error", err)
+			result.Results[i].Error = "unable to remove execution history: " + err.Error()
+		}
+	}
+	result.Applied = true
+	return result, nil, http.StatusOK
+}
+
+// markNotApplied marks all successful results as rolled back (before the failed index) or as not applied (after it)
+func markNotApplied(results []model.BulkResult, failed int) {
+	for i := range results {
+		if i == failed || results[i].Status != http.StatusOK {
+			continue
+		}
+		results[i].Entry = nil
+		results[i].Status = http.StatusFailedDependency
+		if i > failed {
+			results[i].Error = model.ErrorNotApplied.Error()
+		} else if results[i].Error == "" {
+			results[i].Error = model.ErrorRolledBack.Error()
+		}
+	}
+}
+
+func (this *Scheduler) applyBulkOperation(ctx context.Context, op model.BulkOperation, user string) (result *model.ScheduleEntry, rollback func() error, err error, code int) {
+	if op.Op == model.BulkOpCreate {
+		entry := *op.Entry
+		entry.Id = uuid.New().String()
+		unlock := this.locks.Lock(entry.Id)
+		entry, err, code := this.create(ctx, entry, user)
+		unlock()
+		if err != nil {
+			return nil, nil, err, code
+		}
+		return &entry, func() error {
+			defer this.locks.Lock(entry.Id)()
+			err, _ := this.remove(ctx, entry.Id, user)
+			if err != nil {
+				return err
+			}
+			return this.persistence.RemoveExecutions(ctx, entry.Id, user)
+		}, nil, http.StatusOK
+	}
+
+	defer this.locks.Lock(op.Id)()
+	old, err := this.persistence.Get(ctx, op.Id, user)
+	if err != nil {
+		return nil, nil, err, getErrCode(err)
+	}
+	if op.Version != nil && *op.Version != old.Version {
+		return nil, nil, model.ErrorVersionConflict, getErrCode(model.ErrorVersionConflict)
+	}
+
+	if op.Op == model.BulkOpDelete {
+		err, code = this.remove(ctx, op.Id, user)
+		if err != nil {
+			return nil, nil, err, code
+		}
+		return nil, func() error {
+			return this.recreate(ctx, old)
+		}, nil, http.StatusOK
+	}
+
+	entry := old
+	switch op.Op {
+	case model.BulkOpUpdate:
+		entry = *op.Entry
+	case model.BulkOpEnable:
+		disabled := false
+		entry.Disabled = &disabled
+	case model.BulkOpDisable:
+		disabled := true
+		entry.Disabled = &disabled
+	}
+	updated, err, code := this.update(ctx, old, entry, &old.Version)
+	if err != nil {
+		return nil, nil, err, code
+	}
+	return &updated, func() error {
+		return this.restore(ctx, old, updated.Version)
+	}, nil, http.StatusOK
+}
+
+// restore stores old again (including its version) if the entry still has the version written by the bulk operation;
+// the checks of update are not repeated. The paused state and the last firing are managed by the service and kept.
+func (this *Scheduler) restore(ctx context.Context, old model.ScheduleEntry, expectedVersion int64) error {
+	defer this.locks.Lock(old.Id)()
+	current, err := this.persistence.Get(ctx, old.Id, old.User)
+	if err != nil {
+		return err
+	}
+	paused, err := this.isPaused(ctx, old)
+	if err != nil {
+		return err
+	}
+	old.Paused = current.Paused
+	old.LastFiredAt = current.LastFiredAt
+	old.NextRun = old.GetNextRun(time.Now())
+	err = this.persistence.Update(ctx, old, expectedVersion)
+	if err != nil {
+		this.reconcileAfterError(ctx, old.Id, old.User)
+		return err
+	}
+	err = this.addCron(old)
+	if err != nil || old.Paused == paused {
+		return err
+	}
+	return this.setPaused(ctx, &old, paused)
+}
+
+// recreate stores a deleted entry again
+func (this *Scheduler) recreate(ctx context.Context, old model.ScheduleEntry) error {
+	defer this.locks.Lock(old.Id)()
+	err := this.persistence.Set(ctx, old)
+	if err != nil {
+		this.reconcileAfterError(ctx, old.Id, old.User)
+		return err
+	}
+	return this.addCron(old)
+}
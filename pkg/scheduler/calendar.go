@@ -0,0 +1,174 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"time"
+)
+
+const DEFAULT_PREVIEW_COUNT = 10
+const MAX_PREVIEW_COUNT = 100
+
+// MAX_PREVIEW_ITERATIONS bounds the search for runs that are not skipped (e.g. if a calendar excludes nearly every day)
+const MAX_PREVIEW_ITERATIONS = 10000
+
+func (this *Scheduler) ListCalendars(ctx context.Context, user string) (result []model.Calendar, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.list_calendars", "", user)
+	defer func() { end(err) }()
+	result, err = this.persistence.ListCalendars(ctx, user)
+	return result, err, getErrCode(err)
+}
+
+func (this *Scheduler) GetCalendar(ctx context.Context, name string, user string) (result model.Calendar, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.get_calendar", "", user)
+	defer func() { end(err) }()
+	result, err = this.persistence.GetCalendar(ctx, name, user)
+	return result, err, getErrCode(err)
+}
+
+// SetCalendar creates or replaces the calendar; schedules referencing it use the new dates from their next firing on
+func (this *Scheduler) SetCalendar(ctx context.Context, calendar model.Calendar, user string) (result model.Calendar, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.set_calendar", "", user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	calendar.User = user
+	calendar.UpdatedAt = time.Now()
+	err = calendar.Validate()
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	err = this.persistence.SetCalendar(ctx, calendar)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	return calendar, nil, http.StatusOK
+}
+
+// RemoveCalendar removes the calendar; calendars that are referenced by schedules can not be removed (model.ErrorCalendarInUse)
+func (this *Scheduler) RemoveCalendar(ctx context.Context, name string, user string) (err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.remove_calendar", "", user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return err, getErrCode(err)
+	}
+	references, err := this.persistence.CountCalendarReferences(ctx, name, user)
+	if err != nil {
+		return err, getErrCode(err)
+	}
+	if references > 0 {
+		return model.ErrorCalendarInUse, getErrCode(model.ErrorCalendarInUse)
+	}
+	err = this.persistence.RemoveCalendar(ctx, name, user)
+	return err, getErrCode(err)
+}
+
+// NextRuns previews the next count runs of the entry that are neither excluded by a calendar nor suppressed by a blackout window;
+// if includeSkipped is true, the skipped firings in between are part of the result
+func (this *Scheduler) NextRuns(ctx context.Context, id string, user string, count int, includeSkipped bool) (result []model.PlannedRun, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.next_runs", id, user)
+	defer func() { end(err) }()
+	entry, err := this.persistence.Get(ctx, id, user)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	result = []model.PlannedRun{}
+	if !entry.IsScheduled() {
+		return result, nil, http.StatusOK
+	}
+	schedule, err := entry.Schedule()
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	filter, err := this.loadCalendarFilter(ctx, entry)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	t := time.Now()
+	for i, runs := 0, 0; runs < count && i < MAX_PREVIEW_ITERATIONS; i++ {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		run := model.PlannedRun{Time: t}
+		if window, _, blocked := this.activeBlackout(t); blocked {
+			run.Skipped, run.Reason = true, blackoutReason(window)
+		} else if allowed, reason := filter.Allows(t); !allowed {
+			run.Skipped, run.Reason = true, reason
+		}
+		if !run.Skipped {
+			runs++
+		} else if !includeSkipped {
+			continue
+		}
+		result = append(result, run)
+	}
+	return result, nil, http.StatusOK
+}
+
+// checkCalendars returns model.ErrorUnknownCalendar if the entry references a calendar that does not exist
+func (this *Scheduler) checkCalendars(ctx context.Context, entry model.ScheduleEntry) error {
+	_, err := this.loadCalendars(ctx, entry)
+	return err
+}
+
+func (this *Scheduler) loadCalendarFilter(ctx context.Context, entry model.ScheduleEntry) (model.CalendarFilter, error) {
+	calendars, err := this.loadCalendars(ctx, entry)
+	if err != nil {
+		return model.CalendarFilter{}, err
+	}
+	return model.NewCalendarFilter(entry, calendars), nil
+}
+
+// loadCalendars returns the calendars referenced by entry by name
+func (this *Scheduler) loadCalendars(ctx context.Context, entry model.ScheduleEntry) (map[string]model.Calendar, error) {
+	result := map[string]model.Calendar{}
+	for _, name := range entry.CalendarNames() {
+		if _, ok := result[name]; ok {
+			continue
+		}
+		calendar, err := this.persistence.GetCalendar(ctx, name, entry.User)
+		if err == model.ErrorNotFound {
+			return result, fmt.Errorf("%w: %v", model.ErrorUnknownCalendar, name)
+		}
+		if err != nil {
+			return result, err
+		}
+		result[name] = calendar
+	}
+	return result, nil
+}
+
+// calendarSkipReason returns the reason if the calendars of the entry exclude a firing planned at t; empty if the firing is allowed
+func (this *Scheduler) calendarSkipReason(ctx context.Context, entry model.ScheduleEntry, planned time.Time) (reason string, err error) {
+	if len(entry.CalendarNames()) == 0 {
+		return "", nil
+	}
+	filter, err := this.loadCalendarFilter(ctx, entry)
+	if err != nil {
+		return "", err
+	}
+	_, reason = filter.Allows(planned)
+	return reason, nil
+}
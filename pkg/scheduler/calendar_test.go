@@ -0,0 +1,147 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendars(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+
+	today := time.Now()
+	tomorrow := today.AddDate(0, 0, 1)
+
+	t.Run("invalid calendar", func(t *testing.T) {
+		_, err, code := s.SetCalendar(ctx, model.Calendar{Name: "holidays", Dates: []string{"foo"}}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+	t.Run("unknown calendar reference", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 12 * * *", ProcessDeploymentId: "d", ExcludeCalendars: []string{"holidays"}}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+	t.Run("set calendar", func(t *testing.T) {
+		_, err, _ := s.SetCalendar(ctx, model.Calendar{Name: "holidays", Dates: []string{tomorrow.Format(model.CalendarDateFormat), today.Format(model.CalendarDateFormat)}}, "user")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("calendars are separated by user", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 12 * * *", ProcessDeploymentId: "d", ExcludeCalendars: []string{"holidays"}}, "other")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+
+	excluded, _, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 12 * * *", ProcessDeploymentId: "d", ExcludeCalendars: []string{"holidays"}}, "user")
+	included, _, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 12 * * *", ProcessDeploymentId: "d", IncludeCalendars: []string{"holidays"}}, "user")
+
+	t.Run("excluded day is recorded as skipped", func(t *testing.T) {
+		s.runJob(excluded, time.Now())
+		executions := p.executionsOf(excluded.Id)
+		if len(executions) != 1 || executions[0].Status != model.ExecutionStatusSkipped || !strings.Contains(executions[0].Reason, "holidays") {
+			t.Error(executions)
+		}
+		if process.count(excluded.Id) != 0 {
+			t.Error("process has been started")
+		}
+	})
+	t.Run("included day runs", func(t *testing.T) {
+		s.runJob(included, time.Now())
+		if process.count(included.Id) != 1 {
+			t.Error("process has not been started")
+		}
+	})
+
+	t.Run("preview skips excluded days", func(t *testing.T) {
+		runs, err, _ := s.NextRuns(ctx, excluded.Id, "user", 2, false)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(runs) != 2 {
+			t.Error(runs)
+			return
+		}
+		for _, run := range runs {
+			date := run.Time.Format(model.CalendarDateFormat)
+			if run.Skipped || date == today.Format(model.CalendarDateFormat) || date == tomorrow.Format(model.CalendarDateFormat) {
+				t.Error(run)
+			}
+		}
+	})
+	t.Run("preview with skipped runs", func(t *testing.T) {
+		runs, err, _ := s.NextRuns(ctx, excluded.Id, "user", 2, true)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		skipped := 0
+		for _, run := range runs {
+			if run.Skipped {
+				skipped++
+				if run.Reason == "" {
+					t.Error(run)
+				}
+			}
+		}
+		//today noon may already be over
+		if skipped < 1 || len(runs)-skipped != 2 {
+			t.Error(runs)
+		}
+	})
+	t.Run("preview of include calendar", func(t *testing.T) {
+		runs, err, _ := s.NextRuns(ctx, included.Id, "user", 5, false)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(runs) == 0 || len(runs) > 2 {
+			t.Error("expect only runs on calendar days", runs)
+		}
+	})
+
+	t.Run("referenced calendar can not be removed", func(t *testing.T) {
+		err, code := s.RemoveCalendar(ctx, "holidays", "user")
+		expectCode(t, err, code, http.StatusConflict)
+	})
+	t.Run("remove calendar", func(t *testing.T) {
+		_, err, _ := s.Patch(ctx, excluded.Id, "user", []byte(`{"exclude_calendars":null}`), nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err, _ = s.Delete(ctx, included.Id, "user")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err, _ = s.RemoveCalendar(ctx, "holidays", "user")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err, code := s.RemoveCalendar(ctx, "holidays", "user")
+		expectCode(t, err, code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+func (this *Scheduler) Export(ctx context.Context, user string) (result model.ExportDocument, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.export", "", user)
+	defer func() { end(err) }()
+	entries, _, err := this.persistence.List(ctx, user, model.ListOptions{SortBy: model.SortByCreatedAt})
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	result = model.ExportDocument{
+		Version:    model.ExportDocumentVersion,
+		ExportedAt: time.Now(),
+		Schedules:  []model.ExportedSchedule{},
+	}
+	for _, entry := range entries {
+		result.Schedules = append(result.Schedules, model.NewExportedSchedule(entry))
+	}
+	return result, nil, http.StatusOK
+}
+
+// Import creates the schedules of the document; existing ids are handled according to request.Strategy.
+// With request.DryRun the resulting actions are reported without changing anything.
+func (this *Scheduler) Import(ctx context.Context, request model.ImportRequest, user string) (result model.ImportResponse, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.import", "", user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = request.Validate()
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	result = model.ImportResponse{DryRun: request.DryRun, Results: []model.ImportResult{}}
+	handled := map[string]bool{}
+	for _, schedule := range request.Document.Schedules {
+		entry := schedule.ToEntry()
+		item := model.ImportResult{SourceId: schedule.Id, TargetId: schedule.Id}
+		if mapped, ok := request.IdMapping[schedule.Id]; ok {
+			item.TargetId = mapped
+		}
+		if mapped, ok := request.DeploymentMapping[entry.ProcessDeploymentId]; ok {
+			entry.ProcessDeploymentId = mapped
+		}
+		entry.Id = item.TargetId
+		item.Action, err = this.importEntry(ctx, entry, user, request.Strategy, request.DryRun, handled[item.TargetId], &item.TargetId)
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			handled[item.TargetId] = true
+		}
+		result.Results = append(result.Results, item)
+	}
+	return result, nil, http.StatusOK
+}
+
+// importEntry applies a single imported entry; knownExisting signals that a previous entry of the same import used the id
+func (this *Scheduler) importEntry(ctx context.Context, entry model.ScheduleEntry, user string, strategy string, dryRun bool, knownExisting bool, targetId *string) (action string, err error) {
+	err = entry.Validate()
+	if err != nil {
+		return model.ImportActionError, err
+	}
+	var existing *model.ScheduleEntry
+	if entry.Id != "" {
+		defer this.locks.Lock(entry.Id)()
+		old, err := this.persistence.Get(ctx, entry.Id, user)
+		if err == nil {
+			existing = &old
+		} else if err != model.ErrorNotFound {
+			return model.ImportActionError, err
+		}
+	}
+	exists := existing != nil || knownExisting
+
+	switch {
+	case entry.Id == "" || (exists && strategy == model.ImportStrategyDuplicate):
+		if exists {
+			action = model.ImportActionDuplicate
+		} else {
+			action = model.ImportActionCreate
+		}
+		entry.Id = uuid.New().String()
+	case !exists:
+		action = model.ImportActionCreate
+		err = model.ValidateId(entry.Id)
+		if err != nil {
+			return model.ImportActionError, err
+		}
+		//ids are unique across users; the dry run has to report ids of other users like the import would
+		used, err := this.persistence.IdExists(ctx, entry.Id)
+		if err != nil {
+			return model.ImportActionError, err
+		}
+		if used {
+			return model.ImportActionConflict, model.ErrorDuplicateId
+		}
+	case strategy == model.ImportStrategySkip:
+		return model.ImportActionSkip, nil
+	default:
+		action = model.ImportActionOverwrite
+	}
+	*targetId = entry.Id
+	if dryRun {
+		return action, nil
+	}
+	if action == model.ImportActionOverwrite {
+		if existing == nil {
+			old, err := this.persistence.Get(ctx, entry.Id, user)
+			if err != nil {
+				return model.ImportActionError, err
+			}
+			existing = &old
+		}
+		_, err, _ = this.update(ctx, *existing, entry, nil)
+	} else {
+		_, err, _ = this.create(ctx, entry, user)
+	}
+	if err == model.ErrorDuplicateId {
+		return model.ImportActionConflict, err
+	}
+	if err != nil {
+		return model.ImportActionError, err
+	}
+	return action, nil
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"time"
+)
+
+const HEALTH_CHECK_TIMEOUT = 2 * time.Second
+
+var ErrorNotStarted = errors.New("scheduler has not loaded its entries or the cron loop is not running")
+
+// Ready checks the components needed to serve requests and to execute schedules
+func (this *Scheduler) Ready(ctx context.Context) (result model.HealthReport) {
+	ctx, cancel := context.WithTimeout(ctx, HEALTH_CHECK_TIMEOUT)
+	defer cancel()
+	result = model.NewHealthReport()
+	result.Set(model.HealthComponentMongo, this.persistence.Ping(ctx))
+	if this.running.Load() {
+		result.Components[model.HealthComponentScheduler] = model.ComponentHealth{
+			Status:  model.HealthStatusUp,
+			Message: fmt.Sprintf("%v entries loaded", this.loaded.Load()),
+		}
+	} else {
+		result.Set(model.HealthComponentScheduler, ErrorNotStarted)
+	}
+	//every instance schedules all entries; there is no leader election
+	result.Components[model.HealthComponentLeader] = model.ComponentHealth{Status: model.HealthStatusNotApplicable}
+	result.Set(model.HealthComponentProcessEndpoint, this.processes.Ping(ctx))
+	return result
+}
@@ -16,16 +16,56 @@
 
 package scheduler
 
-import "github.com/SENERGY-Platform/process-scheduler/pkg/model"
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"time"
+)
 
 type ProcessApi interface {
-	Execute(entry model.ScheduleEntry)
+	Execute(ctx context.Context, entry model.ScheduleEntry) (statusCode int, err error)
+	Ping(ctx context.Context) error
 }
 
 type Persistence interface {
-	GetAll() ([]model.ScheduleEntry, error)
-	Set(entry model.ScheduleEntry) error
-	Get(id string, userId string) (model.ScheduleEntry, error)
-	Remove(id string, user string) error
-	List(user string, createdBy *string) ([]model.ScheduleEntry, error)
+	Ping(ctx context.Context) error
+
+	GetAll(ctx context.Context) ([]model.ScheduleEntry, error)
+	Set(ctx context.Context, entry model.ScheduleEntry) error
+	Update(ctx context.Context, entry model.ScheduleEntry, expectedVersion int64) error
+	Get(ctx context.Context, id string, userId string) (model.ScheduleEntry, error)
+	IdExists(ctx context.Context, id string) (bool, error)
+	Remove(ctx context.Context, id string, user string) error
+	List(ctx context.Context, user string, options model.ListOptions) (result []model.ScheduleEntry, total int64, err error)
+	SetNextRun(ctx context.Context, id string, user string, next *time.Time) error
+	SetFired(ctx context.Context, id string, user string, firedAt time.Time, next *time.Time) error
+	SetPaused(ctx context.Context, id string, user string, paused bool, next *time.Time) error
+
+	ListPauses(ctx context.Context, user string) ([]model.PauseRecord, error)
+	GetAllPauses(ctx context.Context) ([]model.PauseRecord, error)
+	AddPause(ctx context.Context, record model.PauseRecord) error
+	RemovePause(ctx context.Context, key string, user string) error
+
+	AddExecution(ctx context.Context, execution model.Execution) error
+	GetLastExecution(ctx context.Context, scheduleId string, user string, statuses ...string) (model.Execution, error)
+	CountExecutions(ctx context.Context, scheduleId string, user string, status string, since time.Time) (int64, error)
+	RemoveExecutions(ctx context.Context, scheduleId string, user string) error
+
+	ListBlackoutWindows(ctx context.Context) ([]model.BlackoutWindow, error)
+	SetBlackoutWindow(ctx context.Context, window model.BlackoutWindow) error
+	RemoveBlackoutWindow(ctx context.Context, id string) error
+
+	ListCalendars(ctx context.Context, user string) ([]model.Calendar, error)
+	GetCalendar(ctx context.Context, name string, user string) (model.Calendar, error)
+	SetCalendar(ctx context.Context, calendar model.Calendar) error
+	RemoveCalendar(ctx context.Context, name string, user string) error
+	CountCalendarReferences(ctx context.Context, name string, user string) (int64, error)
+
+	ListQuotaOverrides(ctx context.Context) ([]model.QuotaOverride, error)
+	GetQuotaOverride(ctx context.Context, user string) (model.QuotaOverride, error)
+	SetQuotaOverride(ctx context.Context, override model.QuotaOverride) error
+	RemoveQuotaOverride(ctx context.Context, user string) error
+	CountSchedules(ctx context.Context, user string, deploymentId string) (model.ScheduleCounts, error)
+	LockQuota(ctx context.Context, user string, token string, until time.Time) (acquired bool, err error)
+	UnlockQuota(ctx context.Context, user string, token string) error
 }
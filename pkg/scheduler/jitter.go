@@ -0,0 +1,107 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"hash/fnv"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// Firings may be delayed to avoid load peaks at popular times (e.g. "0 * * * *"):
+// the spread window of the configuration delays every cron entry by a fixed offset derived from its id,
+// the jitter of an entry adds a random delay to each firing.
+// The planned time (next_run, last_fired_at, calendar checks) is not affected; blackout windows are checked at the actual start.
+
+// firingDelay returns the spread offset and a random jitter delay of the next firing of entry
+func (this *Scheduler) firingDelay(entry model.ScheduleEntry) (result time.Duration) {
+	if entry.Cron != "" {
+		result = spreadOffset(entry.Id, this.spreadWindow)
+	}
+	jitter, err := entry.GetJitter()
+	if err == nil && jitter > 0 {
+		result += rand.N(jitter + 1)
+	}
+	return result
+}
+
+// delayFiring waits for the firing delay of entry and returns the actual delay;
+// returns false if the scheduler is stopped in the meantime
+func (this *Scheduler) delayFiring(ctx context.Context, span trace.Span, entry model.ScheduleEntry) (time.Duration, bool) {
+	delay := this.firingDelay(entry)
+	if delay <= 0 {
+		return 0, true
+	}
+	span.SetAttributes(attribute.Float64("schedule.delay_seconds", delay.Seconds()))
+	slog.DebugContext(ctx, "delay firing", "delay", delay)
+	start := time.Now()
+	ok := this.waitDelay(delay)
+	return time.Since(start), ok
+}
+
+func formatDelay(delay time.Duration) string {
+	if delay <= 0 {
+		return ""
+	}
+	return delay.Round(time.Millisecond).String()
+}
+
+// waitDelay blocks for delay; returns false if the scheduler is stopped in the meantime
+func (this *Scheduler) waitDelay(delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-this.delayCtx.Done():
+		return false
+	}
+}
+
+// spreadOffset returns a deterministic offset in [0, window) for the schedule id
+func spreadOffset(id string, window time.Duration) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	return time.Duration(hash.Sum64() % uint64(window))
+}
+
+func getSpreadWindow(config configuration.Config) (time.Duration, error) {
+	if config == nil || config.SpreadWindow == "" {
+		return 0, nil
+	}
+	result, err := time.ParseDuration(config.SpreadWindow)
+	if err != nil {
+		return result, fmt.Errorf("invalid spread_window: %w", err)
+	}
+	if result < 0 || result > model.MAX_JITTER {
+		return result, fmt.Errorf("invalid spread_window: expect a duration of at most %v", model.MAX_JITTER)
+	}
+	return result, nil
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpreadOffset(t *testing.T) {
+	window := 10 * time.Minute
+	if spreadOffset("a", 0) != 0 {
+		t.Error("expected no offset without window")
+	}
+	if spreadOffset("a", window) != spreadOffset("a", window) {
+		t.Error("expected deterministic offset")
+	}
+	buckets := map[time.Duration]int{}
+	for i := 0; i < 1000; i++ {
+		offset := spreadOffset(fmt.Sprint("schedule-", i), window)
+		if offset < 0 || offset >= window {
+			t.Fatal(offset)
+		}
+		buckets[offset/time.Minute]++
+	}
+	for minute := time.Duration(0); minute < 10; minute++ {
+		if buckets[minute] < 50 {
+			t.Error("uneven spread", buckets)
+		}
+	}
+}
+
+func TestFiringDelay(t *testing.T) {
+	s := New(&configuration.ConfigStruct{}, newMemoryPersistence(), newProcessMock(), nil, nil)
+	s.spreadWindow = time.Minute
+	cron := model.ScheduleEntry{Id: "a", Cron: "0 * * * *", Jitter: "10s"}
+	for i := 0; i < 100; i++ {
+		delay := s.firingDelay(cron)
+		offset := spreadOffset("a", time.Minute)
+		if delay < offset || delay > offset+10*time.Second {
+			t.Fatal(delay, offset)
+		}
+	}
+	interval := model.ScheduleEntry{Id: "a", Interval: &model.IntervalTrigger{Every: "PT1H", Anchor: time.Now()}}
+	if delay := s.firingDelay(interval); delay != 0 {
+		t.Error("expected no spread of interval triggers", delay)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+
+	t.Run("invalid jitter", func(t *testing.T) {
+		for _, jitter := range []string{"2h", "-1s", "5 minutes"} {
+			_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d", Jitter: jitter}, "user")
+			expectCode(t, err, code, http.StatusBadRequest)
+		}
+	})
+
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d", Jitter: "300ms"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("delay is recorded", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			s.runJob(entry, time.Now())
+		}
+		executions := p.executionsOf(entry.Id)
+		if len(executions) != 3 || process.count(entry.Id) != 3 {
+			t.Error(executions)
+			return
+		}
+		for _, execution := range executions {
+			if execution.Delay == "" {
+				continue //the random delay may be 0
+			}
+			delay, err := time.ParseDuration(execution.Delay)
+			if err != nil || delay > 400*time.Millisecond {
+				t.Error(execution.Delay, err)
+			}
+		}
+	})
+	t.Run("changed entry is dropped", func(t *testing.T) {
+		changed, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d"}, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		//choose a spread window that delays the firing by 200ms to 1s
+		s.spreadWindow = 500 * time.Millisecond
+		for offset := spreadOffset(changed.Id, s.spreadWindow); offset < 200*time.Millisecond; offset = spreadOffset(changed.Id, s.spreadWindow) {
+			s.spreadWindow += time.Millisecond
+		}
+		defer func() { s.spreadWindow = 0 }()
+		done := make(chan struct{})
+		go func() {
+			s.runJob(changed, time.Now())
+			close(done)
+		}()
+		time.Sleep(100 * time.Millisecond)
+		err, _ = s.Delete(ctx, changed.Id, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-done
+		if executions := p.executionsOf(changed.Id); len(executions) != 0 || process.count(changed.Id) != 0 {
+			t.Error(executions)
+		}
+	})
+}
+
+func TestJitterStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	p := newMemoryPersistence()
+	s := New(&configuration.ConfigStruct{ShutdownGracePeriod: "10s", ReconcileInterval: "0", SpreadWindow: "1h"}, p, newProcessMock(), nil, nil)
+	err := s.Start(ctx, wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d", Jitter: "1h"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.runJob(entry, time.Now())
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	cancel()
+	wg.Wait()
+	<-done
+	if time.Since(start) > 5*time.Second {
+		t.Error("stop waited for the firing delay")
+	}
+	executions := p.executionsOf(entry.Id)
+	if len(executions) != 1 || executions[0].Status != model.ExecutionStatusSkipped || executions[0].Delay == "" {
+		t.Error(executions)
+	}
+}
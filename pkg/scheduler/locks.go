@@ -0,0 +1,57 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import "sync"
+
+// entryLocks serializes operations on the same schedule entry; locks are removed when no longer used
+type entryLocks struct {
+	mux   sync.Mutex
+	locks map[string]*entryLock
+}
+
+type entryLock struct {
+	mux   sync.Mutex
+	users int
+}
+
+func newEntryLocks() *entryLocks {
+	return &entryLocks{locks: map[string]*entryLock{}}
+}
+
+// Lock blocks until the entry with id is free; the returned function releases it
+func (this *entryLocks) Lock(id string) (unlock func()) {
+	this.mux.Lock()
+	lock, ok := this.locks[id]
+	if !ok {
+		lock = &entryLock{}
+		this.locks[id] = lock
+	}
+	lock.users++
+	this.mux.Unlock()
+
+	lock.mux.Lock()
+	return func() {
+		lock.mux.Unlock()
+		this.mux.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(this.locks, id)
+		}
+		this.mux.Unlock()
+	}
+}
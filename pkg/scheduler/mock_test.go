@@ -0,0 +1,555 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryPersistence is an in memory implementation of Persistence for unit tests
+type memoryPersistence struct {
+	mux        sync.Mutex
+	entries    map[string]model.ScheduleEntry //user+id -> entry
+	executions []model.Execution
+	blackouts  map[string]model.BlackoutWindow
+	calendars  map[string]model.Calendar      //user+name -> calendar
+	quotas     map[string]model.QuotaOverride //user -> override
+	quotaLocks map[string]memoryQuotaLock     //user -> lock
+	pauses     map[string]model.PauseRecord   //user+key -> record
+	faults     map[string]fault               //operation -> next fault of this operation
+}
+
+type memoryQuotaLock struct {
+	token string
+	until time.Time
+}
+
+// fault is returned once by the next call of the operation; if afterWrite is true, the write is applied before the error is returned
+type fault struct {
+	err        error
+	afterWrite bool
+}
+
+func newMemoryPersistence() *memoryPersistence {
+	return &memoryPersistence{entries: map[string]model.ScheduleEntry{}, blackouts: map[string]model.BlackoutWindow{}, calendars: map[string]model.Calendar{}, quotas: map[string]model.QuotaOverride{}, quotaLocks: map[string]memoryQuotaLock{}, pauses: map[string]model.PauseRecord{}, faults: map[string]fault{}}
+}
+
+func (this *memoryPersistence) injectFault(operation string, err error, afterWrite bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.faults[operation] = fault{err: err, afterWrite: afterWrite}
+}
+
+// fault returns and removes the injected error of the operation for the given phase; expects this.mux to be locked
+func (this *memoryPersistence) fault(operation string, afterWrite bool) error {
+	f, ok := this.faults[operation]
+	if !ok || f.afterWrite != afterWrite {
+		return nil
+	}
+	delete(this.faults, operation)
+	return f.err
+}
+
+func memoryKey(id string, user string) string {
+	return user + "/" + id
+}
+
+func (this *memoryPersistence) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (this *memoryPersistence) GetAll(ctx context.Context) (result []model.ScheduleEntry, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err = this.fault("get_all", false); err != nil {
+		return nil, err
+	}
+	for _, entry := range this.entries {
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+func (this *memoryPersistence) Set(ctx context.Context, entry model.ScheduleEntry) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("set", false); err != nil {
+		return err
+	}
+	this.entries[memoryKey(entry.Id, entry.User)] = entry
+	return this.fault("set", true)
+}
+
+func (this *memoryPersistence) Update(ctx context.Context, entry model.ScheduleEntry, expectedVersion int64) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("update", false); err != nil {
+		return err
+	}
+	old, ok := this.entries[memoryKey(entry.Id, entry.User)]
+	if !ok {
+		return model.ErrorNotFound
+	}
+	if old.Version != expectedVersion || old.Paused != entry.Paused {
+		return model.ErrorVersionConflict
+	}
+	this.entries[memoryKey(entry.Id, entry.User)] = entry
+	return this.fault("update", true)
+}
+
+func (this *memoryPersistence) IdExists(ctx context.Context, id string) (bool, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, entry := range this.entries {
+		if entry.Id == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *memoryPersistence) Get(ctx context.Context, id string, user string) (model.ScheduleEntry, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("get", false); err != nil {
+		return model.ScheduleEntry{}, err
+	}
+	entry, ok := this.entries[memoryKey(id, user)]
+	if !ok {
+		return entry, model.ErrorNotFound
+	}
+	return entry, nil
+}
+
+func (this *memoryPersistence) Remove(ctx context.Context, id string, user string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("remove", false); err != nil {
+		return err
+	}
+	delete(this.entries, memoryKey(id, user))
+	return this.fault("remove", true)
+}
+
+// List supports the deployment, tag, disabled and paused filters and sorts by id
+func (this *memoryPersistence) List(ctx context.Context, user string, options model.ListOptions) (result []model.ScheduleEntry, total int64, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, entry := range this.entries {
+		if entry.User == user && matchesListOptions(entry, options) {
+			result = append(result, entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id < result[j].Id
+	})
+	total = int64(len(result))
+	if options.Offset > 0 {
+		result = result[min(options.Offset, total):]
+	}
+	if options.Limit > 0 {
+		result = result[:min(options.Limit, int64(len(result)))]
+	}
+	return result, total, nil
+}
+
+func matchesListOptions(entry model.ScheduleEntry, options model.ListOptions) bool {
+	if options.ProcessDeploymentId != nil && *options.ProcessDeploymentId != entry.ProcessDeploymentId {
+		return false
+	}
+	if options.Disabled != nil && *options.Disabled != entry.IsDisabled() {
+		return false
+	}
+	if options.Paused != nil && *options.Paused != entry.Paused {
+		return false
+	}
+	for _, tag := range options.Tags {
+		if !contains(entry.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *memoryPersistence) SetNextRun(ctx context.Context, id string, user string, next *time.Time) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	entry, ok := this.entries[memoryKey(id, user)]
+	if ok {
+		entry.NextRun = next
+		this.entries[memoryKey(id, user)] = entry
+	}
+	return nil
+}
+
+func (this *memoryPersistence) SetFired(ctx context.Context, id string, user string, firedAt time.Time, next *time.Time) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	entry, ok := this.entries[memoryKey(id, user)]
+	if ok {
+		entry.NextRun = next
+		entry.LastFiredAt = &firedAt
+		this.entries[memoryKey(id, user)] = entry
+	}
+	return nil
+}
+
+func (this *memoryPersistence) SetPaused(ctx context.Context, id string, user string, paused bool, next *time.Time) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("set_paused", false); err != nil {
+		return err
+	}
+	entry, ok := this.entries[memoryKey(id, user)]
+	if !ok {
+		return model.ErrorNotFound
+	}
+	entry.Paused = paused
+	entry.NextRun = next
+	this.entries[memoryKey(id, user)] = entry
+	return this.fault("set_paused", true)
+}
+
+func (this *memoryPersistence) ListPauses(ctx context.Context, user string) (result []model.PauseRecord, err error) {
+	all, err := this.GetAllPauses(ctx)
+	result = []model.PauseRecord{}
+	for _, record := range all {
+		if record.User == user {
+			result = append(result, record)
+		}
+	}
+	return result, err
+}
+
+func (this *memoryPersistence) GetAllPauses(ctx context.Context) (result []model.PauseRecord, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result = []model.PauseRecord{}
+	for _, record := range this.pauses {
+		result = append(result, record)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PausedAt.Before(result[j].PausedAt)
+	})
+	return result, nil
+}
+
+func (this *memoryPersistence) AddPause(ctx context.Context, record model.PauseRecord) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("add_pause", false); err != nil {
+		return err
+	}
+	if _, ok := this.pauses[memoryKey(record.Key, record.User)]; !ok {
+		this.pauses[memoryKey(record.Key, record.User)] = record
+	}
+	return nil
+}
+
+func (this *memoryPersistence) RemovePause(ctx context.Context, key string, user string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if _, ok := this.pauses[memoryKey(key, user)]; !ok {
+		return model.ErrorNotFound
+	}
+	delete(this.pauses, memoryKey(key, user))
+	return nil
+}
+
+func (this *memoryPersistence) AddExecution(ctx context.Context, execution model.Execution) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.executions = append(this.executions, execution)
+	return nil
+}
+
+func (this *memoryPersistence) RemoveExecutions(ctx context.Context, scheduleId string, user string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if err := this.fault("remove_executions", false); err != nil {
+		return err
+	}
+	kept := []model.Execution{}
+	for _, execution := range this.executions {
+		if execution.ScheduleId != scheduleId || execution.User != user {
+			kept = append(kept, execution)
+		}
+	}
+	this.executions = kept
+	return nil
+}
+
+func (this *memoryPersistence) GetLastExecution(ctx context.Context, scheduleId string, user string, statuses ...string) (result model.Execution, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	found := false
+	for _, execution := range this.executions {
+		if execution.ScheduleId != scheduleId || execution.User != user {
+			continue
+		}
+		if len(statuses) > 0 && !contains(statuses, execution.Status) {
+			continue
+		}
+		if !found || execution.StartedAt.After(result.StartedAt) {
+			result = execution
+			found = true
+		}
+	}
+	if !found {
+		return result, model.ErrorNotFound
+	}
+	return result, nil
+}
+
+func (this *memoryPersistence) CountExecutions(ctx context.Context, scheduleId string, user string, status string, since time.Time) (result int64, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, execution := range this.executions {
+		if execution.ScheduleId == scheduleId && execution.User == user && execution.Status == status && execution.StartedAt.After(since) {
+			result++
+		}
+	}
+	return result, nil
+}
+
+func (this *memoryPersistence) ListBlackoutWindows(ctx context.Context) (result []model.BlackoutWindow, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result = []model.BlackoutWindow{}
+	for _, window := range this.blackouts {
+		result = append(result, window)
+	}
+	return result, nil
+}
+
+func (this *memoryPersistence) SetBlackoutWindow(ctx context.Context, window model.BlackoutWindow) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.blackouts[window.Id] = window
+	return nil
+}
+
+func (this *memoryPersistence) RemoveBlackoutWindow(ctx context.Context, id string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if _, ok := this.blackouts[id]; !ok {
+		return model.ErrorNotFound
+	}
+	delete(this.blackouts, id)
+	return nil
+}
+
+func (this *memoryPersistence) ListCalendars(ctx context.Context, user string) (result []model.Calendar, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result = []model.Calendar{}
+	for _, calendar := range this.calendars {
+		if calendar.User == user {
+			result = append(result, calendar)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (this *memoryPersistence) GetCalendar(ctx context.Context, name string, user string) (model.Calendar, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	calendar, ok := this.calendars[memoryKey(name, user)]
+	if !ok {
+		return calendar, model.ErrorNotFound
+	}
+	return calendar, nil
+}
+
+func (this *memoryPersistence) SetCalendar(ctx context.Context, calendar model.Calendar) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.calendars[memoryKey(calendar.Name, calendar.User)] = calendar
+	return nil
+}
+
+func (this *memoryPersistence) RemoveCalendar(ctx context.Context, name string, user string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if _, ok := this.calendars[memoryKey(name, user)]; !ok {
+		return model.ErrorNotFound
+	}
+	delete(this.calendars, memoryKey(name, user))
+	return nil
+}
+
+func (this *memoryPersistence) CountCalendarReferences(ctx context.Context, name string, user string) (result int64, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, entry := range this.entries {
+		if entry.User == user && contains(entry.CalendarNames(), name) {
+			result++
+		}
+	}
+	return result, nil
+}
+
+func (this *memoryPersistence) ListQuotaOverrides(ctx context.Context) (result []model.QuotaOverride, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result = []model.QuotaOverride{}
+	for _, override := range this.quotas {
+		result = append(result, override)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].User < result[j].User
+	})
+	return result, nil
+}
+
+func (this *memoryPersistence) GetQuotaOverride(ctx context.Context, user string) (model.QuotaOverride, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	override, ok := this.quotas[user]
+	if !ok {
+		return override, model.ErrorNotFound
+	}
+	return override, nil
+}
+
+func (this *memoryPersistence) SetQuotaOverride(ctx context.Context, override model.QuotaOverride) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.quotas[override.User] = override
+	return nil
+}
+
+func (this *memoryPersistence) RemoveQuotaOverride(ctx context.Context, user string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if _, ok := this.quotas[user]; !ok {
+		return model.ErrorNotFound
+	}
+	delete(this.quotas, user)
+	return nil
+}
+
+func (this *memoryPersistence) LockQuota(ctx context.Context, user string, token string, until time.Time) (bool, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	lock, ok := this.quotaLocks[user]
+	if ok && lock.token != token && !lock.until.Before(time.Now()) {
+		return false, nil
+	}
+	this.quotaLocks[user] = memoryQuotaLock{token: token, until: until}
+	return true, nil
+}
+
+func (this *memoryPersistence) UnlockQuota(ctx context.Context, user string, token string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if lock, ok := this.quotaLocks[user]; ok && lock.token == token {
+		delete(this.quotaLocks, user)
+	}
+	return nil
+}
+
+func (this *memoryPersistence) CountSchedules(ctx context.Context, user string, deploymentId string) (result model.ScheduleCounts, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, entry := range this.entries {
+		if entry.User != user {
+			continue
+		}
+		result.Total++
+		if !entry.IsDisabled() {
+			result.Enabled++
+		}
+		if entry.ProcessDeploymentId == deploymentId {
+			result.Deployment++
+		}
+	}
+	return result, nil
+}
+
+// executionsOf returns the recorded executions of the schedule in order of their recording
+func (this *memoryPersistence) executionsOf(scheduleId string) (result []model.Execution) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, execution := range this.executions {
+		if execution.ScheduleId == scheduleId {
+			result = append(result, execution)
+		}
+	}
+	return result
+}
+
+func contains(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
+
+// processMock counts executions per schedule id
+type processMock struct {
+	mux        sync.Mutex
+	executions map[string]int
+}
+
+func newProcessMock() *processMock {
+	return &processMock{executions: map[string]int{}}
+}
+
+func (this *processMock) Execute(ctx context.Context, entry model.ScheduleEntry) (int, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.executions[entry.Id]++
+	return http.StatusOK, nil
+}
+
+func (this *processMock) count(id string) int {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.executions[id]
+}
+
+func (this *processMock) Ping(ctx context.Context) error {
+	return nil
+}
+
+// blockingProcess blocks every process start until release is closed
+type blockingProcess struct {
+	*processMock
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingProcess() *blockingProcess {
+	return &blockingProcess{processMock: newProcessMock(), started: make(chan string, 100), release: make(chan struct{})}
+}
+
+func (this *blockingProcess) Execute(ctx context.Context, entry model.ScheduleEntry) (int, error) {
+	this.started <- entry.Id
+	<-this.release
+	return this.processMock.Execute(ctx, entry)
+}
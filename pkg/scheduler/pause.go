@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"time"
+)
+
+// Pause stops firing of the selected entries without changing their disabled flag.
+// The selector is stored as pause record, so entries that are created or changed later to match the selector are paused as well.
+func (this *Scheduler) Pause(ctx context.Context, selector model.PauseSelector, user string) (result model.PauseResponse, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.pause", "", user)
+	defer func() { end(err) }()
+	return this.setPausedBySelector(ctx, selector, user, true)
+}
+
+// Resume removes the pause record with the same selector; the selected entries restart firing
+// unless another pause record of the user still selects them. Disabled entries stay disabled.
+func (this *Scheduler) Resume(ctx context.Context, selector model.PauseSelector, user string) (result model.PauseResponse, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.resume", "", user)
+	defer func() { end(err) }()
+	return this.setPausedBySelector(ctx, selector, user, false)
+}
+
+// ListPauses returns the pause records of the user
+func (this *Scheduler) ListPauses(ctx context.Context, user string) (result []model.PauseRecord, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.list_pauses", "", user)
+	defer func() { end(err) }()
+	result, err = this.persistence.ListPauses(ctx, user)
+	return result, err, getErrCode(err)
+}
+
+func (this *Scheduler) setPausedBySelector(ctx context.Context, selector model.PauseSelector, user string, paused bool) (result model.PauseResponse, err error, code int) {
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = selector.Validate()
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	if paused {
+		err = this.persistence.AddPause(ctx, model.PauseRecord{Key: selector.Key(), User: user, Selector: selector, PausedAt: time.Now()})
+	} else {
+		err = this.persistence.RemovePause(ctx, selector.Key(), user)
+		if err == model.ErrorNotFound {
+			//entries that are still paused without matching record are resumed anyway
+			err = nil
+		}
+	}
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	entries, _, err := this.persistence.List(ctx, user, selector.ListOptions(paused))
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	result.Ids = []string{}
+	for _, entry := range entries {
+		changed, err := this.applyPauses(ctx, entry.Id, user)
+		if err != nil {
+			if result.Failed == nil {
+				result.Failed = map[string]string{}
+			}
+			result.Failed[entry.Id] = err.Error()
+			continue
+		}
+		if changed {
+			result.Ids = append(result.Ids, entry.Id)
+		}
+	}
+	return result, nil, http.StatusOK
+}
+
+// applyPauses derives the paused state of the stored entry from the pause records of its user;
+// returns false if the entry already had this state or does not exist
+func (this *Scheduler) applyPauses(ctx context.Context, id string, user string) (changed bool, err error) {
+	defer this.locks.Lock(id)()
+	entry, err := this.persistence.Get(ctx, id, user)
+	if err == model.ErrorNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	paused, err := this.isPaused(ctx, entry)
+	if err != nil {
+		return false, err
+	}
+	if entry.Paused == paused {
+		return false, nil
+	}
+	return true, this.setPaused(ctx, &entry, paused)
+}
+
+// isPaused returns true if a pause record of the entry user selects the entry
+func (this *Scheduler) isPaused(ctx context.Context, entry model.ScheduleEntry) (bool, error) {
+	records, err := this.persistence.ListPauses(ctx, entry.User)
+	if err != nil {
+		return false, err
+	}
+	return model.IsPaused(records, entry), nil
+}
+
+// setPaused stores and registers the paused state of the entry; the caller is responsible for the entry lock.
+// The paused state is managed by the service like last_fired_at, so the entry version (and ETag) is not changed.
+func (this *Scheduler) setPaused(ctx context.Context, entry *model.ScheduleEntry, paused bool) error {
+	entry.Paused = paused
+	entry.NextRun = entry.GetNextRun(time.Now())
+	err := this.persistence.SetPaused(ctx, entry.Id, entry.User, paused, entry.NextRun)
+	if err != nil {
+		this.reconcileAfterError(ctx, entry.Id, entry.User)
+		return err
+	}
+	return this.addCron(*entry)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+)
+
+func TestPauseResume(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	bTrue := true
+
+	create := func(deploymentId string, disabled *bool, tags ...string) string {
+		entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: deploymentId, Disabled: disabled, Tags: tags}, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry.Id
+	}
+	enabledA := create("a", nil, "x")
+	disabledA := create("a", &bTrue)
+	enabledB := create("b", nil, "x")
+	other, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectPaused := func(t *testing.T, expected map[string]bool) {
+		t.Helper()
+		for id, paused := range expected {
+			user := "user"
+			if id == other.Id {
+				user = "other"
+			}
+			entry, err := p.Get(ctx, id, user)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if entry.Paused != paused {
+				t.Error("unexpected paused state", id, entry.Paused, paused)
+			}
+			if entry.Paused && entry.NextRun != nil {
+				t.Error("paused entry has next run", id)
+			}
+		}
+		if entry, _ := p.Get(ctx, disabledA, "user"); !entry.IsDisabled() {
+			t.Error("disabled flag changed")
+		}
+		checkRegistry(t, s, p)
+	}
+
+	t.Run("invalid selector", func(t *testing.T) {
+		_, err, code := s.Pause(ctx, model.PauseSelector{}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+		_, err, code = s.Pause(ctx, model.PauseSelector{All: true, ProcessDeploymentId: "a"}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+
+	t.Run("pause deployment", func(t *testing.T) {
+		result, err, _ := s.Pause(ctx, model.PauseSelector{ProcessDeploymentId: "a"}, "user")
+		if err != nil || len(result.Ids) != 2 {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: true, disabledA: true, enabledB: false, other.Id: false})
+	})
+
+	t.Run("pause tag", func(t *testing.T) {
+		result, err, _ := s.Pause(ctx, model.PauseSelector{Tags: []string{"x"}}, "user")
+		if err != nil || len(result.Ids) != 1 || result.Ids[0] != enabledB {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: true, disabledA: true, enabledB: true, other.Id: false})
+	})
+
+	t.Run("pause keeps version", func(t *testing.T) {
+		entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "c"}, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err, _ = s.Pause(ctx, model.PauseSelector{ProcessDeploymentId: "c"}, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ := p.Get(ctx, entry.Id, "user"); stored.Version != entry.Version || !stored.Paused {
+			t.Error("unexpected version or paused state", stored.Version, stored.Paused)
+		}
+		//an editor holding the version from before the pause is not rejected and keeps the paused state
+		entry.Tags = []string{"edited"}
+		_, err, code := s.Update(ctx, entry, "user", &entry.Version)
+		expectCode(t, err, code, http.StatusOK)
+		expectPaused(t, map[string]bool{entry.Id: true})
+		err, _ = s.Delete(ctx, entry.Id, "user")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("update keeps paused state", func(t *testing.T) {
+		_, err, _ := s.Update(ctx, model.ScheduleEntry{Id: enabledA, Cron: "30 * * * *", ProcessDeploymentId: "a", Tags: []string{"x"}}, "user", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		expectPaused(t, map[string]bool{enabledA: true})
+	})
+
+	t.Run("new entries honor pause records", func(t *testing.T) {
+		byDeployment := create("a", nil)
+		byTag := create("d", nil, "x", "y")
+		unselected := create("d", nil, "y")
+		expectPaused(t, map[string]bool{byDeployment: true, byTag: true, unselected: false})
+		if entry, _ := p.Get(ctx, byDeployment, "user"); entry.NextRun != nil {
+			t.Error("paused entry has next run")
+		}
+		//the update adds the paused tag
+		_, err, _ := s.Update(ctx, model.ScheduleEntry{Id: unselected, Cron: "0 * * * *", ProcessDeploymentId: "d", Tags: []string{"x"}}, "user", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		expectPaused(t, map[string]bool{unselected: true})
+		//the update removes the paused tag
+		_, err, _ = s.Update(ctx, model.ScheduleEntry{Id: byTag, Cron: "0 * * * *", ProcessDeploymentId: "d"}, "user", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		expectPaused(t, map[string]bool{byTag: false})
+		for _, id := range []string{byDeployment, byTag, unselected} {
+			err, _ = s.Delete(ctx, id, "user")
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("list pauses", func(t *testing.T) {
+		_, err, _ := s.Pause(ctx, model.PauseSelector{Tags: []string{"x", "x"}}, "user")
+		if err != nil {
+			t.Error(err)
+		}
+		records, err, _ := s.ListPauses(ctx, "user")
+		if err != nil || len(records) != 3 {
+			t.Error(err, records)
+		}
+		records, err, _ = s.ListPauses(ctx, "other")
+		if err != nil || len(records) != 0 {
+			t.Error(err, records)
+		}
+	})
+
+	t.Run("resume deployment keeps tag pause", func(t *testing.T) {
+		result, err, _ := s.Resume(ctx, model.PauseSelector{ProcessDeploymentId: "a"}, "user")
+		if err != nil || len(result.Ids) != 1 || result.Ids[0] != disabledA {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: true, disabledA: false, enabledB: true, other.Id: false})
+	})
+
+	t.Run("resume tag", func(t *testing.T) {
+		result, err, _ := s.Resume(ctx, model.PauseSelector{Tags: []string{"x"}}, "user")
+		if err != nil || len(result.Ids) != 2 {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: false, disabledA: false, enabledB: false, other.Id: false})
+	})
+
+	t.Run("resume all removes only the all pause", func(t *testing.T) {
+		_, err, _ := s.Pause(ctx, model.PauseSelector{ProcessDeploymentId: "b"}, "user")
+		if err != nil {
+			t.Error(err)
+		}
+		result, err, _ := s.Pause(ctx, model.PauseSelector{All: true}, "user")
+		if err != nil || len(result.Ids) != 2 {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: true, disabledA: true, enabledB: true, other.Id: false})
+		result, err, _ = s.Resume(ctx, model.PauseSelector{All: true}, "user")
+		if err != nil || len(result.Ids) != 2 {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: false, disabledA: false, enabledB: true, other.Id: false})
+		result, err, _ = s.Resume(ctx, model.PauseSelector{ProcessDeploymentId: "b"}, "user")
+		if err != nil || len(result.Ids) != 1 {
+			t.Error(err, result)
+		}
+		expectPaused(t, map[string]bool{enabledA: false, disabledA: false, enabledB: false, other.Id: false})
+		if len(s.cron.Entries()) != 3 {
+			t.Error("unexpected cron entries", len(s.cron.Entries()))
+		}
+	})
+
+	t.Run("reconcile applies pause records", func(t *testing.T) {
+		//simulates an entry that has been created concurrently to the pause of its deployment
+		err := p.AddPause(ctx, model.PauseRecord{Key: model.PauseSelector{ProcessDeploymentId: "b"}.Key(), User: "user", Selector: model.PauseSelector{ProcessDeploymentId: "b"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixed, err := s.reconcile(ctx)
+		if err != nil || fixed != 1 {
+			t.Error(err, fixed)
+		}
+		expectPaused(t, map[string]bool{enabledA: false, disabledA: false, enabledB: true, other.Id: false})
+	})
+}
+
+func TestPauseSelectorKey(t *testing.T) {
+	a := model.PauseSelector{ProcessDeploymentId: "a", Tags: []string{"y", "x", "y"}}
+	b := model.PauseSelector{ProcessDeploymentId: "a", Tags: []string{"x", "y"}}
+	if a.Key() != b.Key() {
+		t.Error(a.Key(), b.Key())
+	}
+	if a.Key() == (model.PauseSelector{Tags: []string{"x", "y"}}).Key() || a.Key() == (model.PauseSelector{All: true}).Key() {
+		t.Error("unexpected equal keys")
+	}
+}
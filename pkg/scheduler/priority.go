@@ -0,0 +1,65 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+)
+
+// checkPriority returns model.ErrorPriorityForbidden if the priority of entry is higher than allowed for the roles in ctx;
+// an unchanged priority of an existing entry (old may be nil) is not checked again
+func (this *Scheduler) checkPriority(ctx context.Context, entry model.ScheduleEntry, old *model.ScheduleEntry) error {
+	if old != nil && old.GetPriority() == entry.GetPriority() {
+		return nil
+	}
+	return entry.ValidateMaxPriority(this.maxPriority(model.GetRoles(ctx)))
+}
+
+// maxPriority returns the highest priority allowed for one of the given roles; the default applies if no role is listed
+func (this *Scheduler) maxPriority(roles []string) string {
+	result, found := "", false
+	for _, role := range roles {
+		if priority, ok := this.maxPriorities[role]; ok && (!found || model.PriorityRank(priority) < model.PriorityRank(result)) {
+			result, found = priority, true
+		}
+	}
+	if found {
+		return result
+	}
+	if priority, ok := this.maxPriorities[model.DefaultRole]; ok {
+		return priority
+	}
+	return model.PriorityNormal
+}
+
+func getMaxPriorities(config configuration.Config) (map[string]string, error) {
+	result := map[string]string{}
+	if config == nil {
+		return result, nil
+	}
+	for role, priority := range config.MaxPriority {
+		entry := model.ScheduleEntry{Priority: priority}
+		if priority == "" || entry.ValidatePriority() != nil {
+			return result, fmt.Errorf("invalid max_priority of role %q: %v", role, priority)
+		}
+		result[role] = priority
+	}
+	return result, nil
+}
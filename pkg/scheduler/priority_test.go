@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriorityRoles(t *testing.T) {
+	s, _, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", MaxPriority: map[string]string{model.DefaultRole: model.PriorityLow, "admin": model.PriorityHigh}})
+	defer stop()
+	ctx := context.Background()
+	adminCtx := model.WithRoles(ctx, []string{"user", "admin"})
+
+	_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "d"}, "user")
+	expectCode(t, err, code, http.StatusForbidden)
+	_, err, code = s.Add(ctx, model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "d", Priority: model.PriorityLow}, "user")
+	expectCode(t, err, code, http.StatusOK)
+	_, err, code = s.Add(ctx, model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "d", Priority: "urgent"}, "user")
+	expectCode(t, err, code, http.StatusBadRequest)
+
+	entry, err, code := s.Add(adminCtx, model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "d", Priority: model.PriorityHigh}, "user")
+	expectCode(t, err, code, http.StatusOK)
+	entry.Tags = []string{"changed"}
+	entry, err, code = s.Update(ctx, entry, "user", nil)
+	expectCode(t, err, code, http.StatusOK)
+	entry.Priority = model.PriorityNormal
+	_, err, code = s.Update(ctx, entry, "user", nil)
+	expectCode(t, err, code, http.StatusForbidden)
+}
+
+func TestMaxPriorityOfRoles(t *testing.T) {
+	s := New(&configuration.ConfigStruct{}, newMemoryPersistence(), newProcessMock(), nil, nil)
+	if priority := s.maxPriority(nil); priority != model.PriorityNormal {
+		t.Error("expected normal without configuration", priority)
+	}
+	s.maxPriorities = map[string]string{model.DefaultRole: model.PriorityLow, "a": model.PriorityNormal, "b": model.PriorityHigh}
+	cases := []struct {
+		roles    []string
+		expected string
+	}{
+		{nil, model.PriorityLow},
+		{[]string{"unknown"}, model.PriorityLow},
+		{[]string{"a"}, model.PriorityNormal},
+		{[]string{"a", "b"}, model.PriorityHigh},
+	}
+	for _, c := range cases {
+		if result := s.maxPriority(c.roles); result != c.expected {
+			t.Error(c.roles, result, c.expected)
+		}
+	}
+	_, err := getMaxPriorities(&configuration.ConfigStruct{MaxPriority: map[string]string{"a": "urgent"}})
+	if err == nil {
+		t.Error("expected invalid max_priority error")
+	}
+}
+
+func TestPriorityOrdersQueuedFirings(t *testing.T) {
+	s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", ShutdownGracePeriod: "1s", WorkerPoolSize: 1, MaxPriority: map[string]string{model.DefaultRole: model.PriorityHigh}})
+	defer stop()
+	process := newBlockingProcess()
+	s.processes = process
+	add := func(priority string) model.ScheduleEntry {
+		entry, err, _ := s.Add(context.Background(), model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d", Priority: priority}, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+	blocker, low, high := add(model.PriorityNormal), add(model.PriorityLow), add(model.PriorityHigh)
+	wg := sync.WaitGroup{}
+	run := func(entry model.ScheduleEntry, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runJob(entry, time.Now())
+		}()
+		if queued > 0 {
+			waitForQueued(t, s.workers, queued)
+		}
+	}
+	run(blocker, 0)
+	<-process.started
+	run(low, 1)
+	run(high, 2)
+
+	//the second firing of low is coalesced with the waiting one
+	s.runJob(low, time.Now())
+	executions := p.executionsOf(low.Id)
+	if len(executions) != 1 || executions[0].Status != model.ExecutionStatusSkipped || executions[0].Reason != errCoalesced.Error() {
+		t.Error("expected coalesced firing", executions)
+	}
+
+	close(process.release)
+	wg.Wait()
+	started := []string{}
+	for len(process.started) > 0 {
+		started = append(started, <-process.started)
+	}
+	if len(started) != 2 || started[0] != high.Id || started[1] != low.Id {
+		t.Error("expected high priority firing first", started, high.Id, low.Id)
+	}
+	if process.count(low.Id) != 1 || len(p.executionsOf(low.Id)) != 2 {
+		t.Error(process.count(low.Id), p.executionsOf(low.Id))
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Quotas are checked and the change is persisted while the quota lock of the user is held,
+// so that concurrent requests of one user can not exceed a quota together.
+// The lock is a document in the database, which makes it effective across instances;
+// it expires after QUOTA_LOCK_LEASE so that a crashed instance can not block the user forever.
+
+const QUOTA_LOCK_LEASE = 30 * time.Second
+const QUOTA_LOCK_WAIT = 10 * time.Second
+const QUOTA_LOCK_RETRY = 20 * time.Millisecond
+
+func (this *Scheduler) ListQuotaOverrides(ctx context.Context) (result []model.QuotaOverride, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.list_quota_overrides", "", "")
+	defer func() { end(err) }()
+	result, err = this.persistence.ListQuotaOverrides(ctx)
+	return result, err, getErrCode(err)
+}
+
+// GetQuota returns the effective quota of the user and the current usage
+func (this *Scheduler) GetQuota(ctx context.Context, user string) (result model.QuotaStatus, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.get_quota", "", user)
+	defer func() { end(err) }()
+	result.Quota, result.Override, err = this.loadQuota(ctx, user)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	counts, err := this.persistence.CountSchedules(ctx, user, "")
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	result.Schedules, result.EnabledSchedules = counts.Total, counts.Enabled
+	return result, nil, http.StatusOK
+}
+
+// SetQuotaOverride replaces the quota override of override.User; existing schedules above the new limits are kept
+func (this *Scheduler) SetQuotaOverride(ctx context.Context, override model.QuotaOverride, admin string) (result model.QuotaOverride, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.set_quota_override", "", override.User)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = override.Validate()
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	override.UpdatedBy = admin
+	override.UpdatedAt = time.Now()
+	err = this.persistence.SetQuotaOverride(ctx, override)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	return override, nil, http.StatusOK
+}
+
+// RemoveQuotaOverride resets the quota of the user to the defaults of the configuration
+func (this *Scheduler) RemoveQuotaOverride(ctx context.Context, user string) (err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.remove_quota_override", "", user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return err, getErrCode(err)
+	}
+	err = this.persistence.RemoveQuotaOverride(ctx, user)
+	return err, getErrCode(err)
+}
+
+// checkQuota returns model.ErrorQuotaExceeded or model.ErrorQuotaForbidden if storing entry for user would exceed the quota;
+// old is the stored version of entry (nil for new entries).
+// On success, the quota lock of user is held until the returned unlock function is called, which must happen after the change is persisted.
+func (this *Scheduler) checkQuota(ctx context.Context, user string, entry model.ScheduleEntry, old *model.ScheduleEntry) (unlock func(), err error) {
+	quota, _, err := this.loadQuota(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if quota == (model.Quota{}) {
+		return func() {}, nil
+	}
+	unlock, err = this.lockQuota(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := this.persistence.CountSchedules(ctx, user, entry.ProcessDeploymentId)
+	if err == nil {
+		err = quota.Check(counts, entry, old)
+	}
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// lockQuota waits up to QUOTA_LOCK_WAIT for the quota lock of user
+func (this *Scheduler) lockQuota(ctx context.Context, user string) (unlock func(), err error) {
+	unlockLocal := this.quotaLocks.Lock(user)
+	token := uuid.New().String()
+	deadline := time.Now().Add(QUOTA_LOCK_WAIT)
+	for {
+		acquired, err := this.persistence.LockQuota(ctx, user, token, time.Now().Add(QUOTA_LOCK_LEASE))
+		if err != nil {
+			unlockLocal()
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			unlockLocal()
+			return nil, model.ErrorQuotaLocked
+		}
+		select {
+		case <-ctx.Done():
+			unlockLocal()
+			return nil, ctx.Err()
+		case <-time.After(QUOTA_LOCK_RETRY):
+		}
+	}
+	return func() {
+		err := this.persistence.UnlockQuota(context.WithoutCancel(ctx), user, token)
+		if err != nil {
+			slog.WarnContext(ctx, "unable to release quota lock; it expires after the lease", "user", user, "error", err)
+		}
+		unlockLocal()
+	}, nil
+}
+
+// loadQuota returns the default quota with the override of the user applied
+func (this *Scheduler) loadQuota(ctx context.Context, user string) (result model.Quota, override *model.QuotaOverride, err error) {
+	result = getDefaultQuota(this.config)
+	stored, err := this.persistence.GetQuotaOverride(ctx, user)
+	if errors.Is(err, model.ErrorNotFound) {
+		return result, nil, nil
+	}
+	if err != nil {
+		return result, nil, err
+	}
+	return stored.Apply(result), &stored, nil
+}
+
+func getDefaultQuota(config configuration.Config) (result model.Quota) {
+	if config == nil {
+		return result
+	}
+	limit := func(value int64) *int64 {
+		if value <= 0 {
+			return nil
+		}
+		return &value
+	}
+	return model.Quota{
+		MaxSchedules:              limit(config.MaxSchedulesPerUser),
+		MaxEnabledSchedules:       limit(config.MaxEnabledSchedulesPerUser),
+		MaxSchedulesPerDeployment: limit(config.MaxSchedulesPerDeployment),
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	s, _, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", MaxSchedulesPerUser: 3, MaxEnabledSchedulesPerUser: 2, MaxSchedulesPerDeployment: 2})
+	defer stop()
+	ctx := context.Background()
+	disabled := true
+
+	first, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err, _ = s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("deployment limit", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a", Disabled: &disabled}, "user")
+		expectCode(t, err, code, http.StatusTooManyRequests)
+	})
+	t.Run("enabled limit", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "b"}, "user")
+		expectCode(t, err, code, http.StatusTooManyRequests)
+	})
+	third, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "b", Disabled: &disabled}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("total limit", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "c", Disabled: &disabled}, "user")
+		expectCode(t, err, code, http.StatusTooManyRequests)
+		if !errors.Is(err, model.ErrorQuotaExceeded) {
+			t.Error(err)
+		}
+	})
+	t.Run("other users are independent", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "other")
+		expectCode(t, err, code, http.StatusOK)
+	})
+	t.Run("update within quota", func(t *testing.T) {
+		first.Tags = []string{"changed"}
+		_, err, code := s.Update(ctx, first, "user", nil)
+		expectCode(t, err, code, http.StatusOK)
+	})
+	t.Run("enable above limit", func(t *testing.T) {
+		enabled := false
+		third.Disabled = &enabled
+		_, err, code := s.Update(ctx, third, "user", nil)
+		expectCode(t, err, code, http.StatusTooManyRequests)
+	})
+	t.Run("override", func(t *testing.T) {
+		_, err, code := s.SetQuotaOverride(ctx, model.QuotaOverride{User: "user", MaxSchedules: limit(model.QuotaUnlimited), MaxEnabledSchedules: limit(3)}, "admin")
+		expectCode(t, err, code, http.StatusOK)
+		_, err, code = s.Update(ctx, third, "user", nil)
+		expectCode(t, err, code, http.StatusOK)
+		_, err, code = s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "c", Disabled: &disabled}, "user")
+		expectCode(t, err, code, http.StatusOK)
+		status, err, _ := s.GetQuota(ctx, "user")
+		if err != nil || status.Override == nil || status.Quota.MaxSchedules != nil || status.Schedules != 4 || status.EnabledSchedules != 3 {
+			t.Error(err, status)
+		}
+	})
+	t.Run("forbidden", func(t *testing.T) {
+		_, err, code := s.SetQuotaOverride(ctx, model.QuotaOverride{User: "other", MaxSchedules: limit(0)}, "admin")
+		expectCode(t, err, code, http.StatusOK)
+		_, err, code = s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "other")
+		expectCode(t, err, code, http.StatusForbidden)
+	})
+	t.Run("invalid override", func(t *testing.T) {
+		_, err, code := s.SetQuotaOverride(ctx, model.QuotaOverride{User: "other", MaxSchedules: limit(-5)}, "admin")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+	t.Run("remove override", func(t *testing.T) {
+		err, code := s.RemoveQuotaOverride(ctx, "other")
+		expectCode(t, err, code, http.StatusOK)
+		err, code = s.RemoveQuotaOverride(ctx, "other")
+		expectCode(t, err, code, http.StatusNotFound)
+		overrides, err, _ := s.ListQuotaOverrides(ctx)
+		if err != nil || len(overrides) != 1 || overrides[0].User != "user" || overrides[0].UpdatedBy != "admin" {
+			t.Error(err, overrides)
+		}
+	})
+}
+
+func TestQuotaConcurrentAdd(t *testing.T) {
+	s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", MaxSchedulesPerUser: 5})
+	defer stop()
+	ctx := context.Background()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "user")
+		}()
+	}
+	wg.Wait()
+	counts, err := p.CountSchedules(ctx, "user", "a")
+	if err != nil || counts.Total != 5 {
+		t.Error(err, counts)
+	}
+}
+
+func TestQuotaConcurrentAddInstances(t *testing.T) {
+	config := &configuration.ConfigStruct{ReconcileInterval: "0", MaxSchedulesPerUser: 5}
+	s, p, stop := startTestSchedulerWithConfig(t, config)
+	defer stop()
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s2 := New(config, p, newProcessMock(), nil, nil)
+	err := s2.Start(ctx, wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adds := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		instance := s
+		if i%2 == 1 {
+			instance = s2
+		}
+		adds.Add(1)
+		go func() {
+			defer adds.Done()
+			instance.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "user")
+		}()
+	}
+	adds.Wait()
+	counts, err := p.CountSchedules(ctx, "user", "a")
+	if err != nil || counts.Total != 5 {
+		t.Error(err, counts)
+	}
+	if len(p.quotaLocks) != 0 {
+		t.Error("quota locks not released", p.quotaLocks)
+	}
+}
+
+func TestQuotaExpiredLock(t *testing.T) {
+	s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", MaxSchedulesPerUser: 5})
+	defer stop()
+	ctx := context.Background()
+	_, err := p.LockQuota(ctx, "user", "crashed", time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "a"}, "user")
+	expectCode(t, err, code, http.StatusOK)
+}
+
+func limit(value int64) *int64 {
+	return &value
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// Process starts are limited by token buckets: one for all users and one per user.
+// Firings that exceed a limit are handled by the overflow policy of the configuration:
+//   - queue: the firing waits for a token, but at most MAX_QUEUE_WAIT
+//   - drop: the firing is dropped
+//   - defer: the firing is retried once at the start of the next second and dropped if the limit is still exceeded
+// Dropped firings are recorded with model.ExecutionStatusThrottled.
+
+const OverflowPolicyQueue = "queue"
+const OverflowPolicyDrop = "drop"
+const OverflowPolicyDefer = "defer"
+
+const MAX_QUEUE_WAIT = time.Minute
+
+const rateLimitScopeGlobal = "global"
+const rateLimitScopeUser = "user"
+
+type rateLimiter struct {
+	policy    string
+	global    *rate.Limiter //nil if the global limit is disabled
+	userLimit rate.Limit    //0 if the per user limit is disabled
+	userBurst int
+	mux       sync.Mutex
+	users     map[string]*rate.Limiter
+}
+
+// newRateLimiter returns nil if no limit is configured
+func newRateLimiter(config configuration.Config) (*rateLimiter, error) {
+	if config == nil {
+		return nil, nil
+	}
+	policy := config.ExecutionOverflowPolicy
+	switch policy {
+	case "":
+		policy = OverflowPolicyQueue
+	case OverflowPolicyQueue, OverflowPolicyDrop, OverflowPolicyDefer:
+	default:
+		return nil, fmt.Errorf("invalid execution_overflow_policy %q; expect queue, drop or defer", policy)
+	}
+	if config.ExecutionRateLimit < 0 || config.UserExecutionRateLimit < 0 {
+		return nil, fmt.Errorf("invalid execution rate limit: expect a positive number of executions per second or 0")
+	}
+	if config.ExecutionRateLimit == 0 && config.UserExecutionRateLimit == 0 {
+		return nil, nil
+	}
+	result := &rateLimiter{
+		policy:    policy,
+		userLimit: rate.Limit(config.UserExecutionRateLimit),
+		userBurst: getBurst(config.UserExecutionRateLimit, config.UserExecutionRateBurst),
+		users:     map[string]*rate.Limiter{},
+	}
+	if config.ExecutionRateLimit > 0 {
+		result.global = rate.NewLimiter(rate.Limit(config.ExecutionRateLimit), getBurst(config.ExecutionRateLimit, config.ExecutionRateBurst))
+	}
+	return result, nil
+}
+
+// getBurst defaults to the number of executions per second, but at least 1
+func getBurst(limit float64, burst int64) int {
+	if burst > 0 {
+		return int(burst)
+	}
+	return max(1, int(math.Ceil(limit)))
+}
+
+// startReservation reserves one process start; delay is the wait until the start is allowed, scope names the limit that causes it
+type startReservation struct {
+	at           time.Time
+	delay        time.Duration
+	scope        string
+	reservations []*rate.Reservation
+}
+
+// cancel returns the reserved tokens, including those that were available immediately
+func (this startReservation) cancel() {
+	for _, reservation := range this.reservations {
+		reservation.CancelAt(this.at)
+	}
+}
+
+func (this *rateLimiter) reserve(user string, now time.Time) (result startReservation) {
+	result.at = now
+	if this == nil {
+		return result
+	}
+	if reservation := this.reserveUser(user, now); reservation != nil {
+		result.reservations = append(result.reservations, reservation)
+		if delay := reservation.DelayFrom(now); delay > result.delay {
+			result.delay, result.scope = delay, rateLimitScopeUser
+		}
+	}
+	if this.global != nil {
+		reservation := this.global.ReserveN(now, 1)
+		result.reservations = append(result.reservations, reservation)
+		if delay := reservation.DelayFrom(now); delay > result.delay {
+			result.delay, result.scope = delay, rateLimitScopeGlobal
+		}
+	}
+	return result
+}
+
+// reserveUser reserves one token of the limiter of the user; returns nil if the per user limit is disabled.
+// the reservation is made while this.mux is locked, so that evictIdle can not drop the limiter in between
+func (this *rateLimiter) reserveUser(user string, now time.Time) *rate.Reservation {
+	if this.userLimit == 0 {
+		return nil
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	limiter, ok := this.users[user]
+	if !ok {
+		limiter = rate.NewLimiter(this.userLimit, this.userBurst)
+		this.users[user] = limiter
+	}
+	return limiter.ReserveN(now, 1)
+}
+
+// evictIdle drops the limiters of users with a full bucket, which have not started a process for at least burst/rate;
+// a new limiter starts with a full bucket, so dropping them does not change the limits
+func (this *rateLimiter) evictIdle(now time.Time) (evicted int) {
+	if this == nil {
+		return 0
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for user, limiter := range this.users {
+		if limiter.TokensAt(now) >= float64(this.userBurst) {
+			delete(this.users, user)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// throttle waits until the rate limits allow the process start of entry, according to the overflow policy.
+// returns the time waited and if the start has been throttled; ok is false if the firing has been dropped and recorded.
+func (this *Scheduler) throttle(ctx context.Context, span trace.Span, entry model.ScheduleEntry, executionId string, delay time.Duration) (waited time.Duration, throttled bool, ok bool) {
+	reservation := this.limiter.reserve(entry.User, time.Now())
+	if reservation.delay <= 0 {
+		return 0, false, true
+	}
+	span.SetAttributes(attribute.String("execution.throttled_by", reservation.scope))
+	start := time.Now()
+	switch this.limiter.policy {
+	case OverflowPolicyDrop:
+		reservation.cancel()
+	case OverflowPolicyDefer:
+		reservation.cancel()
+		if this.waitDelay(time.Until(start.Truncate(time.Second).Add(time.Second))) {
+			retry := this.limiter.reserve(entry.User, time.Now())
+			if retry.delay <= 0 {
+				this.metrics.ObserveThrottledExecution(reservation.scope, "deferred")
+				return time.Since(start), true, true
+			}
+			retry.cancel()
+			reservation.scope = retry.scope
+		}
+	default:
+		if reservation.delay <= MAX_QUEUE_WAIT {
+			if this.waitDelay(reservation.delay) {
+				this.metrics.ObserveThrottledExecution(reservation.scope, "queued")
+				return time.Since(start), true, true
+			}
+		}
+		reservation.cancel()
+	}
+	this.metrics.ObserveThrottledExecution(reservation.scope, "dropped")
+	this.recordThrottled(ctx, span, entry, executionId, reservation.scope+" execution rate limit exceeded", delay+time.Since(start))
+	slog.WarnContext(ctx, "execution dropped by rate limit", "scope", reservation.scope, "policy", this.limiter.policy)
+	return time.Since(start), true, false
+}
+
+// recordThrottled records a firing that has been dropped to limit the load of the process engine
+func (this *Scheduler) recordThrottled(ctx context.Context, span trace.Span, entry model.ScheduleEntry, executionId string, reason string, delay time.Duration) {
+	now := time.Now()
+	execution := model.Execution{
+		Id:         executionId,
+		ScheduleId: entry.Id,
+		User:       entry.User,
+		Status:     model.ExecutionStatusThrottled,
+		Reason:     reason,
+		Delay:      formatDelay(delay),
+		Throttled:  true,
+		StartedAt:  now,
+		FinishedAt: now,
+	}
+	span.SetAttributes(attribute.String("execution.id", execution.Id), attribute.String("execution.status", execution.Status), attribute.String("execution.reason", execution.Reason))
+	err := this.persistence.AddExecution(ctx, execution)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to store execution", "error", err)
+	}
+}
+
+// checkMinInterval returns model.ErrorIntervalTooShort if entry fires more often than allowed for the roles in ctx;
+// unchanged triggers of existing entries (old may be nil) are not checked again
+func (this *Scheduler) checkMinInterval(ctx context.Context, entry model.ScheduleEntry, old *model.ScheduleEntry) error {
+	if old != nil && old.SameTrigger(entry) {
+		return nil
+	}
+	return entry.ValidateMinInterval(this.minInterval(model.GetRoles(ctx)))
+}
+
+// minInterval returns the most permissive min interval of the given roles; the default applies if no role is listed
+func (this *Scheduler) minInterval(roles []string) time.Duration {
+	result, found := time.Duration(0), false
+	for _, role := range roles {
+		if interval, ok := this.minIntervals[role]; ok && (!found || interval < result) {
+			result, found = interval, true
+		}
+	}
+	if !found {
+		return this.minIntervals[model.DefaultRole]
+	}
+	return result
+}
+
+func getMinIntervals(config configuration.Config) (map[string]time.Duration, error) {
+	result := map[string]time.Duration{}
+	if config == nil {
+		return result, nil
+	}
+	for role, value := range config.MinExecutionInterval {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return result, fmt.Errorf("invalid min_execution_interval of role %q: %v", role, value)
+		}
+		result[role] = interval
+	}
+	return result, nil
+}
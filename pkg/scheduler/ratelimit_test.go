@@ -0,0 +1,229 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter, err := newRateLimiter(&configuration.ConfigStruct{ExecutionRateLimit: 10, ExecutionRateBurst: 3, UserExecutionRateLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	first := limiter.reserve("a", now)
+	second := limiter.reserve("a", now)
+	if first.delay != 0 || second.delay <= 0 || second.scope != rateLimitScopeUser {
+		t.Error(first, second)
+	}
+	second.cancel()
+	if other := limiter.reserve("b", now); other.delay != 0 {
+		t.Error("users should be limited independently", other)
+	}
+	if third := limiter.reserve("c", now); third.delay != 0 {
+		t.Error(third)
+	}
+	if global := limiter.reserve("d", now); global.delay <= 0 || global.scope != rateLimitScopeGlobal {
+		t.Error("expected global limit", global)
+	}
+
+	none, err := newRateLimiter(&configuration.ConfigStruct{})
+	if err != nil || none != nil || none.reserve("a", now).delay != 0 {
+		t.Error(err, none)
+	}
+	_, err = newRateLimiter(&configuration.ConfigStruct{ExecutionRateLimit: 1, ExecutionOverflowPolicy: "retry"})
+	if err == nil {
+		t.Error("expected invalid policy error")
+	}
+}
+
+func TestRateLimiterEvictIdle(t *testing.T) {
+	limiter, err := newRateLimiter(&configuration.ConfigStruct{UserExecutionRateLimit: 1, UserExecutionRateBurst: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	limiter.reserve("a", now)
+	limiter.reserve("b", now)
+	limiter.reserve("b", now)
+	if evicted := limiter.evictIdle(now); evicted != 0 {
+		t.Error("limiters in use should be kept", evicted)
+	}
+	if evicted := limiter.evictIdle(now.Add(time.Second)); evicted != 1 || len(limiter.users) != 1 {
+		t.Error("expected eviction of a", evicted, len(limiter.users))
+	}
+	if evicted := limiter.evictIdle(now.Add(2 * time.Second)); evicted != 1 || len(limiter.users) != 0 {
+		t.Error("expected eviction of b", evicted, len(limiter.users))
+	}
+	if reservation := limiter.reserve("b", now.Add(2*time.Second)); reservation.delay != 0 {
+		t.Error("new limiter should start with a full bucket", reservation)
+	}
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", UserExecutionRateLimit: 1, ExecutionOverflowPolicy: OverflowPolicyDrop})
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d"}, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		s.runJob(entry, time.Now())
+	}
+	s.runJob(other, time.Now())
+	if process.count(entry.Id) != 1 || process.count(other.Id) != 1 {
+		t.Error(process.count(entry.Id), process.count(other.Id))
+	}
+	throttled := 0
+	for _, execution := range p.executionsOf(entry.Id) {
+		if execution.Status == model.ExecutionStatusThrottled {
+			throttled++
+			if !execution.Throttled || execution.Reason == "" {
+				t.Error(execution)
+			}
+		}
+	}
+	if throttled != 2 {
+		t.Error("unexpected throttled count", throttled)
+	}
+}
+
+func TestRateLimitQueue(t *testing.T) {
+	s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", ExecutionRateLimit: 5, ExecutionRateBurst: 1})
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		s.runJob(entry, time.Now())
+	}
+	if duration := time.Since(start); duration < 350*time.Millisecond {
+		t.Error("expected queued executions", duration)
+	}
+	executions := p.executionsOf(entry.Id)
+	if process.count(entry.Id) != 3 || len(executions) != 3 {
+		t.Error(process.count(entry.Id), executions)
+		return
+	}
+	throttled := 0
+	for _, execution := range executions {
+		if execution.Status != model.ExecutionStatusSuccess {
+			t.Error(execution)
+		}
+		if execution.Throttled {
+			throttled++
+			if execution.Delay == "" {
+				t.Error("missing delay", execution)
+			}
+		}
+	}
+	if throttled != 2 {
+		t.Error("unexpected throttled count", throttled)
+	}
+}
+
+func TestRateLimitDefer(t *testing.T) {
+	run := func(t *testing.T, limit float64) (executions []model.Execution, process *processMock) {
+		s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", UserExecutionRateLimit: limit, UserExecutionRateBurst: 1, ExecutionOverflowPolicy: OverflowPolicyDefer})
+		defer stop()
+		entry, err, _ := s.Add(context.Background(), model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d"}, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		//start right after a full second; the second firing is deferred by nearly a second
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second + 10*time.Millisecond)))
+		s.runJob(entry, time.Now())
+		s.runJob(entry, time.Now())
+		if time.Now().Nanosecond() > int(100*time.Millisecond) {
+			t.Error("expected the retry at the start of the next second", time.Now())
+		}
+		process = s.processes.(*processMock)
+		return p.executionsOf(entry.Id), process
+	}
+	t.Run("token available at next second", func(t *testing.T) {
+		executions, process := run(t, 2)
+		if len(executions) != 2 || process.count(executions[0].ScheduleId) != 2 || !executions[1].Throttled || executions[1].Delay == "" {
+			t.Error(executions)
+		}
+	})
+	t.Run("token not available at next second", func(t *testing.T) {
+		executions, process := run(t, 0.5)
+		if len(executions) != 2 || process.count(executions[0].ScheduleId) != 1 || executions[1].Status != model.ExecutionStatusThrottled {
+			t.Error(executions)
+		}
+	})
+}
+
+func TestMinInterval(t *testing.T) {
+	s, _, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", MinExecutionInterval: map[string]string{model.DefaultRole: "1m", "admin": "1s"}})
+	defer stop()
+	ctx := context.Background()
+	adminCtx := model.WithRoles(ctx, []string{"user", "admin"})
+
+	_, err, code := s.Add(ctx, model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "d"}, "user")
+	expectCode(t, err, code, http.StatusBadRequest)
+	_, err, code = s.Add(ctx, model.ScheduleEntry{Interval: &model.IntervalTrigger{Every: "PT30S", Anchor: time.Now()}, ProcessDeploymentId: "d"}, "user")
+	expectCode(t, err, code, http.StatusBadRequest)
+	_, err, code = s.Add(ctx, model.ScheduleEntry{Cron: "0,30 0 * * *", ProcessDeploymentId: "d"}, "user")
+	expectCode(t, err, code, http.StatusOK)
+	_, err, code = s.Add(ctx, model.ScheduleEntry{Cron: "0,30 0 * * * *", ProcessDeploymentId: "d"}, "user")
+	expectCode(t, err, code, http.StatusBadRequest)
+
+	entry, err, code := s.Add(adminCtx, model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "d"}, "user")
+	expectCode(t, err, code, http.StatusOK)
+	entry.Tags = []string{"changed"}
+	_, err, code = s.Update(ctx, entry, "user", nil)
+	expectCode(t, err, code, http.StatusOK)
+	entry.Cron = "*/2 * * * * *"
+	_, err, code = s.Update(ctx, entry, "user", nil)
+	expectCode(t, err, code, http.StatusBadRequest)
+}
+
+func TestMinIntervalOfRoles(t *testing.T) {
+	s := New(&configuration.ConfigStruct{}, newMemoryPersistence(), newProcessMock(), nil, nil)
+	s.minIntervals = map[string]time.Duration{model.DefaultRole: time.Hour, "a": time.Minute, "b": time.Second}
+	cases := []struct {
+		roles    []string
+		expected time.Duration
+	}{
+		{nil, time.Hour},
+		{[]string{"unknown"}, time.Hour},
+		{[]string{"a"}, time.Minute},
+		{[]string{"a", "b"}, time.Second},
+	}
+	for _, c := range cases {
+		if result := s.minInterval(c.roles); result != c.expected {
+			t.Error(c.roles, result)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/logging"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// The database is the source of truth; the cron registry is derived from it.
+// Every write is persisted first and registered afterwards. If a write fails, it may have been applied partially,
+// so the registration of the entry is derived from the stored state again. Divergences that can not be resolved
+// immediately (e.g. because the database is unavailable) are resolved by the periodic reconciliation.
+
+const DEFAULT_RECONCILE_INTERVAL = time.Minute
+
+// reconcileAfterError derives the registration of the entry from the database after a failed write
+func (this *Scheduler) reconcileAfterError(ctx context.Context, id string, user string) {
+	_, err := this.reconcileEntry(ctx, id, user)
+	if err != nil {
+		slog.WarnContext(ctx, "unable to reconcile entry after failed write; retry with next reconciliation", "error", err)
+	}
+}
+
+// reconcileEntry derives the registration of the entry from the database; returns true if the registration changed.
+// the caller is responsible for the entry lock
+func (this *Scheduler) reconcileEntry(ctx context.Context, id string, user string) (changed bool, err error) {
+	stored, err := this.persistence.Get(ctx, id, user)
+	if err == model.ErrorNotFound {
+		return this.removeCron(id, user), nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if this.isRegistered(stored) {
+		return false, nil
+	}
+	return true, this.addCron(stored)
+}
+
+// reconcile compares the cron registry with the database and corrects diverged registrations and paused states
+// (e.g. of entries created while a matching pause record was added); returns the number of corrected entries
+func (this *Scheduler) reconcile(ctx context.Context) (fixed int, err error) {
+	ctx, span := this.tracing.Start(ctx, "scheduler.reconcile")
+	defer func() { tracing.End(span, err) }()
+	entries, err := this.persistence.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pauses, err := this.persistence.GetAllPauses(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pausesByUser := map[string][]model.PauseRecord{}
+	for _, record := range pauses {
+		pausesByUser[record.User] = append(pausesByUser[record.User], record)
+	}
+	pauseCandidates := map[string]string{} //id -> user
+	candidates := map[string]string{}      //id -> user
+	stored := map[string]bool{}
+	for _, entry := range entries {
+		stored[entry.Id] = true
+		if model.IsPaused(pausesByUser[entry.User], entry) != entry.Paused {
+			pauseCandidates[entry.Id] = entry.User
+		}
+		if !this.isRegistered(entry) {
+			candidates[entry.Id] = entry.User
+		}
+	}
+	this.mux.Lock()
+	for id, reg := range this.registered {
+		if !stored[id] {
+			candidates[id] = reg.user
+		}
+	}
+	this.mux.Unlock()
+
+	//the lists may be outdated because of concurrent changes; each candidate is checked again while its entry is locked
+	for id, user := range pauseCandidates {
+		entryCtx := logging.WithScheduleId(logging.WithUser(ctx, user), id)
+		changed, err := this.applyPauses(entryCtx, id, user)
+		if err != nil {
+			slog.ErrorContext(entryCtx, "unable to apply pause records to entry", "error", err)
+			continue
+		}
+		if changed {
+			fixed++
+			slog.WarnContext(entryCtx, "paused state diverged from pause records; paused state corrected")
+		}
+	}
+	for id, user := range candidates {
+		entryCtx := logging.WithScheduleId(logging.WithUser(ctx, user), id)
+		unlock := this.locks.Lock(id)
+		changed, err := this.reconcileEntry(entryCtx, id, user)
+		unlock()
+		if err != nil {
+			slog.ErrorContext(entryCtx, "unable to reconcile entry", "error", err)
+			continue
+		}
+		if changed {
+			fixed++
+			slog.WarnContext(entryCtx, "cron registry diverged from database; registration corrected")
+		}
+	}
+	this.metrics.AddReconciledEntries(fixed)
+	return fixed, nil
+}
+
+// startReconciliation reconciles the cron registry and the blackout window cache with the database every interval until ctx is done;
+// idle per user rate limiters are dropped on the same interval
+func (this *Scheduler) startReconciliation(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if this.stopping.Load() {
+					return
+				}
+				_, err := this.reconcile(ctx)
+				if err != nil {
+					slog.Error("unable to reconcile cron registry", "error", err)
+				}
+				err = this.loadBlackoutWindows(ctx)
+				if err != nil {
+					slog.Error("unable to reload blackout windows", "error", err)
+				}
+				this.limiter.evictIdle(time.Now())
+			}
+		}
+	}()
+}
+
+func getReconcileInterval(config configuration.Config) (time.Duration, error) {
+	if config == nil || config.ReconcileInterval == "" {
+		return DEFAULT_RECONCILE_INTERVAL, nil
+	}
+	result, err := time.ParseDuration(config.ReconcileInterval)
+	if err != nil {
+		return result, fmt.Errorf("invalid reconcile_interval: %w", err)
+	}
+	return result, nil
+}
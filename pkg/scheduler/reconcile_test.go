@@ -0,0 +1,239 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errInjected = errors.New("injected fault")
+
+func TestWriteFaults(t *testing.T) {
+	bTrue := true
+	entry := model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d"}
+
+	type testCase struct {
+		name  string
+		run   func(t *testing.T, s *Scheduler, p *memoryPersistence, id string)
+		stays bool //the entry created before the run is expected to exist afterwards
+	}
+	cases := []testCase{
+		{name: "create fails before write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("set", errInjected, false)
+			_, err, code := s.Add(context.Background(), entry, "user")
+			expectCode(t, err, code, http.StatusInternalServerError)
+			expectEntryCount(t, p, 1)
+		}, stays: true},
+		{name: "create fails after write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("set", errInjected, true)
+			_, err, code := s.Add(context.Background(), entry, "user")
+			expectCode(t, err, code, http.StatusInternalServerError)
+			expectEntryCount(t, p, 2)
+		}, stays: true},
+		{name: "update fails before write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("update", errInjected, false)
+			_, err, code := s.Update(context.Background(), model.ScheduleEntry{Id: id, Cron: "0 * * * *", ProcessDeploymentId: "d", Disabled: &bTrue}, "user", nil)
+			expectCode(t, err, code, http.StatusInternalServerError)
+		}, stays: true},
+		{name: "update fails after write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("update", errInjected, true)
+			_, err, code := s.Update(context.Background(), model.ScheduleEntry{Id: id, Cron: "0 * * * *", ProcessDeploymentId: "d", Disabled: &bTrue}, "user", nil)
+			expectCode(t, err, code, http.StatusInternalServerError)
+		}, stays: true},
+		{name: "patch fails after write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("update", errInjected, true)
+			_, err, code := s.Patch(context.Background(), id, "user", []byte(`{"cron":"30 * * * *"}`), nil)
+			expectCode(t, err, code, http.StatusInternalServerError)
+		}, stays: true},
+		{name: "delete fails before write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("remove", errInjected, false)
+			err, code := s.Delete(context.Background(), id, "user")
+			expectCode(t, err, code, http.StatusInternalServerError)
+		}, stays: true},
+		{name: "delete fails after write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("remove", errInjected, true)
+			err, code := s.Delete(context.Background(), id, "user")
+			expectCode(t, err, code, http.StatusInternalServerError)
+		}},
+		{name: "delete of other user", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			err, code := s.Delete(context.Background(), id, "other")
+			expectCode(t, err, code, http.StatusOK)
+		}, stays: true},
+		{name: "bulk rollback fails", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("set", errInjected, false)
+			result, err, code := s.Bulk(context.Background(), model.BulkRequest{Atomic: true, Operations: []model.BulkOperation{
+				{Op: model.BulkOpDelete, Id: id},
+				{Op: model.BulkOpEnable, Id: "unknown"},
+			}}, "user")
+			expectCode(t, err, code, http.StatusNotFound)
+			if len(result.Results) != 2 || result.Results[0].Error == "" {
+				t.Error("expected rollback error", result.Results)
+			}
+		}},
+		{name: "bulk rollback fails after write", run: func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("set", errInjected, true)
+			_, err, code := s.Bulk(context.Background(), model.BulkRequest{Atomic: true, Operations: []model.BulkOperation{
+				{Op: model.BulkOpDelete, Id: id},
+				{Op: model.BulkOpEnable, Id: "unknown"},
+			}}, "user")
+			expectCode(t, err, code, http.StatusNotFound)
+		}, stays: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, p, stop := startTestScheduler(t, "0")
+			defer stop()
+			created, err, _ := s.Add(context.Background(), entry, "user")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			c.run(t, s, p, created.Id)
+			_, err = p.Get(context.Background(), created.Id, "user")
+			if (err == nil) != c.stays {
+				t.Error("unexpected stored state", err)
+			}
+			checkRegistry(t, s, p)
+		})
+	}
+}
+
+// if the stored state can not be read after a failed write, or if the service crashed between write and registration,
+// the next reconciliation corrects the registry
+func TestReconcileAfterUnresolvedFault(t *testing.T) {
+	entry := model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d"}
+	cases := map[string]func(t *testing.T, s *Scheduler, p *memoryPersistence, id string){
+		"create": func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("set", errInjected, true)
+			p.injectFault("get", errInjected, false)
+			_, err, code := s.Add(context.Background(), entry, "user")
+			expectCode(t, err, code, http.StatusInternalServerError)
+		},
+		"delete": func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			p.injectFault("remove", errInjected, true)
+			p.injectFault("get", errInjected, false)
+			err, code := s.Delete(context.Background(), id, "user")
+			expectCode(t, err, code, http.StatusInternalServerError)
+		},
+		"crash after create": func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			_ = p.Set(context.Background(), model.ScheduleEntry{Id: "written-by-crashed-request", User: "user", Cron: "0 * * * *", Version: 1})
+		},
+		"crash after update": func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			stored, _ := p.Get(context.Background(), id, "user")
+			disabled := true
+			stored.Disabled = &disabled
+			stored.Version++
+			_ = p.Update(context.Background(), stored, stored.Version-1)
+		},
+		"crash after delete": func(t *testing.T, s *Scheduler, p *memoryPersistence, id string) {
+			_ = p.Remove(context.Background(), id, "user")
+		},
+	}
+	for name, run := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, p, stop := startTestScheduler(t, "0")
+			defer stop()
+			created, err, _ := s.Add(context.Background(), entry, "user")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			run(t, s, p, created.Id)
+			fixed, err := s.reconcile(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if fixed == 0 {
+				t.Error("expected corrected registrations")
+			}
+			checkRegistry(t, s, p)
+			fixed, err = s.reconcile(context.Background())
+			if err != nil || fixed != 0 {
+				t.Error("reconciliation is not idempotent", fixed, err)
+			}
+		})
+	}
+}
+
+func TestReconcileReadFault(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	_, err, _ := s.Add(context.Background(), model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d"}, "user")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	p.injectFault("get_all", errInjected, false)
+	_, err = s.reconcile(context.Background())
+	if !errors.Is(err, errInjected) {
+		t.Error(err)
+	}
+	checkRegistry(t, s, p)
+}
+
+func TestPeriodicReconciliation(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "20ms")
+	defer stop()
+	entry := model.ScheduleEntry{Id: "direct", User: "user", Cron: "0 * * * *", Version: 1}
+	_ = p.Set(context.Background(), entry)
+	timeout := time.After(2 * time.Second)
+	for !s.isRegistered(entry) {
+		select {
+		case <-timeout:
+			t.Error("entry was not registered by the periodic reconciliation")
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	checkRegistry(t, s, p)
+}
+
+// startTestScheduler starts a scheduler with in memory persistence; stop waits until the scheduler is stopped
+func startTestScheduler(t *testing.T, reconcileInterval string) (s *Scheduler, p *memoryPersistence, stop func()) {
+	return startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ShutdownGracePeriod: "1s", ReconcileInterval: reconcileInterval})
+}
+
+func startTestSchedulerWithConfig(t *testing.T, config configuration.Config) (s *Scheduler, p *memoryPersistence, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	p = newMemoryPersistence()
+	s = New(config, p, newProcessMock(), nil, nil)
+	err := s.Start(ctx, wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, p, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func expectEntryCount(t *testing.T, p *memoryPersistence, expected int) {
+	t.Helper()
+	entries, _ := p.GetAll(context.Background())
+	if len(entries) != expected {
+		t.Error("unexpected entry count", len(entries), expected)
+	}
+}
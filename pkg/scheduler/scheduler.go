@@ -18,43 +18,156 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/logging"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const DEFAULT_SHUTDOWN_GRACE_PERIOD = 30 * time.Second
+const SHUTDOWN_RECORD_TIMEOUT = 5 * time.Second
+
 type Scheduler struct {
+	config      configuration.Config
 	persistence Persistence
 	processes   ProcessApi
 	cron        *cron.Cron
-	jobById     map[string]cron.EntryID
+	locks       *entryLocks             //serializes changes of the same entry
+	quotaLocks  *entryLocks             //serializes the quota locking of the same user within this instance
+	mux         sync.Mutex              //guards registered
+	registered  map[string]registration //entry id -> cron registration; derived from the persisted entries
+	metrics     *metrics.Metrics
+	tracing     *tracing.Tracing
+	running     atomic.Bool  //entries are loaded and the cron loop is started
+	loaded      atomic.Int64 //number of entries loaded on start
+	stopping    atomic.Bool  //changes are rejected with model.ErrorShuttingDown
+
+	executionCtx     context.Context //parent of all running executions; canceled if the shutdown grace period is exceeded
+	cancelExecutions context.CancelFunc
+	delayCtx         context.Context //canceled on Stop; aborts firings that wait for their jitter, spread or rate limit delay
+	cancelDelays     context.CancelFunc
+	spreadWindow     time.Duration
+	limiter          *rateLimiter             //nil if no execution rate limit is configured
+	minIntervals     map[string]time.Duration //role -> min interval between firings
+	maxPriorities    map[string]string        //role -> highest priority of entries
+	workers          *workerPool              //nil if the number of concurrent process starts is not limited
+
+	blackoutMux sync.RWMutex
+	blackouts   []model.BlackoutWindow //cache of the persisted blackout windows
+
+	misfireMux sync.Mutex
+	misfires   map[string]*time.Timer //entry id -> pending run after a blackout window (misfire policy fire_once)
+	misfireWg  sync.WaitGroup
 }
 
-func New(persistence Persistence, processes ProcessApi) *Scheduler {
+// New creates a scheduler; m and t may be nil
+func New(config configuration.Config, persistence Persistence, processes ProcessApi, m *metrics.Metrics, t *tracing.Tracing) *Scheduler {
+	executionCtx, cancelExecutions := context.WithCancel(context.Background())
+	delayCtx, cancelDelays := context.WithCancel(context.Background())
 	return &Scheduler{
-		persistence: persistence,
-		processes:   processes,
-		jobById:     map[string]cron.EntryID{},
+		config:           config,
+		executionCtx:     executionCtx,
+		cancelExecutions: cancelExecutions,
+		delayCtx:         delayCtx,
+		cancelDelays:     cancelDelays,
+		persistence:      persistence,
+		processes:        processes,
+		locks:            newEntryLocks(),
+		quotaLocks:       newEntryLocks(),
+		registered:       map[string]registration{},
+		misfires:         map[string]*time.Timer{},
+		metrics:          m,
+		tracing:          t,
 	}
 }
 
+// registration is the in memory state of a persisted entry
+type registration struct {
+	user     string
+	version  int64
+	disabled bool
+	paused   bool
+	job      cron.EntryID //only set for scheduled entries
+}
+
+func (this registration) scheduled() bool {
+	return !this.disabled && !this.paused
+}
+
+// Start loads all entries and starts the cron loop and the periodic reconciliation; Stop is called when ctx is done
 func (this *Scheduler) Start(ctx context.Context, wg *sync.WaitGroup) error {
-	this.cron = cron.New(cron.WithParser(cron.NewParser(
-		cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
-	)))
-	entries, err := this.persistence.GetAll()
+	gracePeriod, err := getShutdownGracePeriod(this.config)
 	if err != nil {
 		return err
 	}
+	reconcileInterval, err := getReconcileInterval(this.config)
+	if err != nil {
+		return err
+	}
+	this.spreadWindow, err = getSpreadWindow(this.config)
+	if err != nil {
+		return err
+	}
+	this.limiter, err = newRateLimiter(this.config)
+	if err != nil {
+		return err
+	}
+	this.minIntervals, err = getMinIntervals(this.config)
+	if err != nil {
+		return err
+	}
+	this.maxPriorities, err = getMaxPriorities(this.config)
+	if err != nil {
+		return err
+	}
+	this.workers, err = newWorkerPool(this.config, this.metrics)
+	if err != nil {
+		return err
+	}
+	this.cron = cron.New(cron.WithParser(model.CronParser))
+	loadCtx := ctx
+	if loadCtx == nil {
+		loadCtx = context.Background()
+	}
+	err = this.loadBlackoutWindows(loadCtx)
+	if err != nil {
+		return err
+	}
+	entries, err := this.persistence.GetAll(loadCtx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
 	for _, entry := range entries {
 		err = this.addCron(entry)
 		if err != nil {
 			return err
 		}
+		next := entry.GetNextRun(now)
+		if !equalTimePtr(next, entry.NextRun) {
+			err = this.persistence.SetNextRun(loadCtx, entry.Id, entry.User, next)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	this.cron.Start()
+	this.loaded.Store(int64(len(entries)))
+	this.running.Store(true)
 
 	if ctx != nil {
 		if wg != nil {
@@ -62,96 +175,563 @@ func (this *Scheduler) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		}
 		go func() {
 			<-ctx.Done()
-			this.Stop()
+			this.Stop(gracePeriod)
 			if wg != nil {
 				wg.Done()
 			}
 		}()
+		this.startReconciliation(ctx, wg, reconcileInterval)
 	}
 	return nil
 }
 
-func (this *Scheduler) Stop() {
-	if this.cron != nil {
-		this.cron.Stop()
+// Stop rejects further changes, stops scheduling new firings and waits up to gracePeriod for running executions.
+// Executions that are still running afterwards are canceled; Stop returns as soon as their outcome is recorded.
+func (this *Scheduler) Stop(gracePeriod time.Duration) {
+	this.stopping.Store(true)
+	this.running.Store(false)
+	if this.cron == nil {
+		return
+	}
+	this.cancelMisfires()
+	this.cancelDelays()
+	cronDone := this.cron.Stop().Done()
+	done := make(chan struct{})
+	go func() {
+		<-cronDone
+		this.misfireWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(gracePeriod):
+	}
+	slog.Warn("shutdown grace period exceeded; cancel running executions", "grace_period", gracePeriod.String())
+	this.cancelExecutions()
+	select {
+	case <-done:
+	case <-time.After(SHUTDOWN_RECORD_TIMEOUT):
+		slog.Error("canceled executions did not finish in time; their outcome may be lost")
+	}
+}
+
+// checkAcceptsChanges returns model.ErrorShuttingDown if the scheduler is stopping
+func (this *Scheduler) checkAcceptsChanges() error {
+	if this.stopping.Load() {
+		return model.ErrorShuttingDown
 	}
+	return nil
 }
 
-func (this *Scheduler) Add(entry model.ScheduleEntry, user string) (result model.ScheduleEntry, err error, code int) {
+func (this *Scheduler) Add(ctx context.Context, entry model.ScheduleEntry, user string) (result model.ScheduleEntry, err error, code int) {
 	entry.Id = uuid.New().String()
+	ctx, end := this.startSpan(ctx, "scheduler.add", entry.Id, user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	defer this.locks.Lock(entry.Id)()
+	return this.create(ctx, entry, user)
+}
+
+// create stores a new entry with the id given in entry.Id; the entry is paused if a pause record of the user selects it
+func (this *Scheduler) create(ctx context.Context, entry model.ScheduleEntry, user string) (result model.ScheduleEntry, err error, code int) {
 	entry.User = user
-	err = this.addCron(entry)
+	entry.LastFiredAt = nil
+	entry.Version = 1
+	entry.CreatedAt = time.Now()
+	err = checkSchedule(entry)
 	if err != nil {
 		return entry, err, http.StatusBadRequest
 	}
-	err = this.persistence.Set(entry)
+	err = this.checkMinInterval(ctx, entry, nil)
+	if err != nil {
+		return entry, err, getErrCode(err)
+	}
+	err = this.checkPriority(ctx, entry, nil)
+	if err != nil {
+		return entry, err, getErrCode(err)
+	}
+	err = this.checkCalendars(ctx, entry)
+	if err != nil {
+		return entry, err, getErrCode(err)
+	}
+	entry.Paused, err = this.isPaused(ctx, entry)
+	if err != nil {
+		return entry, err, getErrCode(err)
+	}
+	entry.NextRun = entry.GetNextRun(entry.CreatedAt)
+	unlock, err := this.checkQuota(ctx, user, entry, nil)
+	if err != nil {
+		return entry, err, getErrCode(err)
+	}
+	defer unlock()
+	err = this.persistence.Set(ctx, entry)
+	if err != nil {
+		this.reconcileAfterError(ctx, entry.Id, user)
+		return entry, err, getErrCode(err)
+	}
+	err = this.addCron(entry)
 	if err != nil {
-		this.removeCron(entry.Id)
 		return entry, err, http.StatusInternalServerError
 	}
 	return entry, nil, http.StatusOK
 }
 
-func (this *Scheduler) Update(entry model.ScheduleEntry, user string) (result model.ScheduleEntry, err error, code int) {
-	entry.User = user
-	old, err := this.persistence.Get(entry.Id, user)
+// Update replaces the entry; if expectedVersion is not nil, the update is only applied
+// if the stored entry still has this version (model.ErrorVersionConflict otherwise)
+func (this *Scheduler) Update(ctx context.Context, entry model.ScheduleEntry, user string, expectedVersion *int64) (result model.ScheduleEntry, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.update", entry.Id, user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
 	if err != nil {
 		return result, err, getErrCode(err)
 	}
-	this.removeCron(entry.Id)
-	err = this.addCron(entry)
+	defer this.locks.Lock(entry.Id)()
+	old, err := this.persistence.Get(ctx, entry.Id, user)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	return this.update(ctx, old, entry, expectedVersion)
+}
+
+// Patch applies a JSON Merge Patch (RFC 7396) to the stored entry and updates it like Update would
+func (this *Scheduler) Patch(ctx context.Context, id string, user string, patch []byte, expectedVersion *int64) (result model.ScheduleEntry, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.patch", id, user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	defer this.locks.Lock(id)()
+	old, err := this.persistence.Get(ctx, id, user)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	original, err := json.Marshal(old)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	patched, err := model.ApplyMergePatch(original, patch)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	entry := model.ScheduleEntry{}
+	err = json.Unmarshal(patched, &entry)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	err = entry.ValidateAndEnsureId(id)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	return this.update(ctx, old, entry, expectedVersion)
+}
+
+// update replaces old with the user defined fields of entry; the last firing of old is kept.
+// The paused state of old is kept by the replacement and derived from the pause records afterwards.
+func (this *Scheduler) update(ctx context.Context, old model.ScheduleEntry, entry model.ScheduleEntry, expectedVersion *int64) (result model.ScheduleEntry, err error, code int) {
+	if expectedVersion != nil && *expectedVersion != old.Version {
+		return result, model.ErrorVersionConflict, getErrCode(model.ErrorVersionConflict)
+	}
+	entry.Paused = old.Paused
+	entry.LastFiredAt = old.LastFiredAt
+	err = this.checkMinInterval(ctx, entry, &old)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = this.checkPriority(ctx, entry, &old)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	err = this.checkCalendars(ctx, entry)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	entry.User = old.User
+	paused, err := this.isPaused(ctx, entry)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	unlock, err := this.checkQuota(ctx, old.User, entry, &old)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	defer unlock()
+	result, err, code = this.replace(ctx, old, entry)
+	if err != nil || result.Paused == paused {
+		return result, err, code
+	}
+	//the changed entry is selected by other pause records than before
+	err = this.setPaused(ctx, &result, paused)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	return result, nil, http.StatusOK
+}
+
+// replace stores entry as the successor of old and registers it
+func (this *Scheduler) replace(ctx context.Context, old model.ScheduleEntry, entry model.ScheduleEntry) (result model.ScheduleEntry, err error, code int) {
+	entry.User = old.User
+	entry.Version = old.Version + 1
+	entry.CreatedAt = old.CreatedAt
+	entry.NextRun = entry.GetNextRun(time.Now())
+	err = checkSchedule(entry)
 	if err != nil {
-		this.addCron(old) //try to recover
 		return entry, err, http.StatusBadRequest
 	}
-	err = this.persistence.Set(entry)
+	err = this.persistence.Update(ctx, entry, old.Version)
+	if err != nil {
+		this.reconcileAfterError(ctx, entry.Id, entry.User)
+		return entry, err, getErrCode(err)
+	}
+	err = this.addCron(entry)
 	if err != nil {
-		this.removeCron(entry.Id)
-		this.addCron(old) //try to recover
 		return entry, err, http.StatusInternalServerError
 	}
 	return entry, nil, http.StatusOK
 }
 
-func (this *Scheduler) Delete(id string, user string) (err error, code int) {
-	err = this.persistence.Remove(id, user)
+func (this *Scheduler) Delete(ctx context.Context, id string, user string) (err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.delete", id, user)
+	defer func() { end(err) }()
+	err = this.checkAcceptsChanges()
+	if err != nil {
+		return err, getErrCode(err)
+	}
+	defer this.locks.Lock(id)()
+	err, code = this.remove(ctx, id, user)
+	if err != nil {
+		return err, code
+	}
+	err = this.persistence.RemoveExecutions(ctx, id, user)
+	return err, getErrCode(err)
+}
+
+// remove deletes the entry and its registration; the execution history is kept, so that a rollback can restore the entry with its history
+func (this *Scheduler) remove(ctx context.Context, id string, user string) (err error, code int) {
+	err = this.persistence.Remove(ctx, id, user)
 	if err != nil {
+		this.reconcileAfterError(ctx, id, user)
 		return err, getErrCode(err)
 	}
-	this.removeCron(id)
+	this.removeCron(id, user)
 	return nil, http.StatusOK
 }
 
-func (this *Scheduler) List(user string, createdBy *string) (result []model.ScheduleEntry, err error, code int) {
-	result, err = this.persistence.List(user, createdBy)
+// Get returns the entry (including its stored next planned run) enriched with its last execution and the number of failed executions since the last successful one
+func (this *Scheduler) Get(ctx context.Context, id string, user string) (result model.ScheduleEntryInfo, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.get", id, user)
+	defer func() { end(err) }()
+	result.ScheduleEntry, err = this.persistence.Get(ctx, id, user)
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	lastRun, err := this.persistence.GetLastExecution(ctx, id, user)
+	if err == model.ErrorNotFound {
+		return result, nil, http.StatusOK
+	}
+	if err != nil {
+		return result, err, getErrCode(err)
+	}
+	result.LastRun = &lastRun
+	failuresSince := time.Time{}
+	lastSuccess, err := this.persistence.GetLastExecution(ctx, id, user, model.ExecutionStatusSuccess)
+	if err == nil {
+		failuresSince = lastSuccess.StartedAt
+	} else if err != model.ErrorNotFound {
+		return result, err, getErrCode(err)
+	}
+	result.FailureCount, err = this.persistence.CountExecutions(ctx, id, user, model.ExecutionStatusFailed, failuresSince)
 	return result, err, getErrCode(err)
 }
 
+func (this *Scheduler) List(ctx context.Context, user string, options model.ListOptions) (result []model.ScheduleEntry, total int64, err error, code int) {
+	ctx, end := this.startSpan(ctx, "scheduler.list", "", user)
+	defer func() { end(err) }()
+	result, total, err = this.persistence.List(ctx, user, options)
+	return result, total, err, getErrCode(err)
+}
+
+// addCron registers the entry; an existing registration of the same id is replaced
 func (this *Scheduler) addCron(entry model.ScheduleEntry) error {
-	if entry.Disabled != nil && *entry.Disabled == true {
-		return nil
+	var schedule cron.Schedule
+	if entry.IsScheduled() {
+		var err error
+		schedule, err = entry.Schedule()
+		if err != nil {
+			return err
+		}
 	}
-	id, err := this.cron.AddFunc(entry.Cron, func() {
-		this.runJob(entry)
-	})
-	if err != nil {
-		return err
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.removeCronUnsafe(entry.Id)
+	reg := registration{user: entry.User, version: entry.Version, disabled: entry.IsDisabled(), paused: entry.Paused}
+	if reg.scheduled() {
+		planned := schedule.Next(time.Now())
+		plannedMux := sync.Mutex{}
+		reg.job = this.cron.Schedule(schedule, cron.FuncJob(func() {
+			plannedMux.Lock()
+			current := planned
+			planned = schedule.Next(time.Now())
+			plannedMux.Unlock()
+			this.runJob(entry, current)
+		}))
 	}
-	this.jobById[entry.Id] = id
+	this.registered[entry.Id] = reg
+	this.updateJobMetrics()
 	return nil
 }
 
-func (this *Scheduler) removeCron(externalId string) {
-	id, ok := this.jobById[externalId]
+// removeCron removes the registration of the entry if it belongs to user; returns true if a registration was removed
+func (this *Scheduler) removeCron(externalId string, user string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if reg, ok := this.registered[externalId]; !ok || reg.user != user {
+		return false
+	}
+	this.removeCronUnsafe(externalId)
+	return true
+}
+
+// removeCronUnsafe expects this.mux to be locked
+func (this *Scheduler) removeCronUnsafe(externalId string) {
+	reg, ok := this.registered[externalId]
 	if !ok {
 		return
 	}
-	this.cron.Remove(id)
-	return
+	delete(this.registered, externalId)
+	if reg.scheduled() {
+		this.cron.Remove(reg.job)
+	}
+	this.updateJobMetrics()
 }
 
-func (this *Scheduler) runJob(entry model.ScheduleEntry) {
-	this.processes.Execute(entry)
+// isRegistered checks if the registration matches the persisted entry
+func (this *Scheduler) isRegistered(entry model.ScheduleEntry) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	reg, ok := this.registered[entry.Id]
+	return ok && reg.user == entry.User && reg.version == entry.Version && reg.disabled == entry.IsDisabled() && reg.paused == entry.Paused
+}
+
+// updateJobMetrics expects this.mux to be locked
+func (this *Scheduler) updateJobMetrics() {
+	disabled := 0
+	paused := 0
+	for _, reg := range this.registered {
+		if reg.disabled {
+			disabled++
+		} else if reg.paused {
+			paused++
+		}
+	}
+	this.metrics.SetJobs(len(this.registered)-disabled-paused, disabled, paused)
+}
+
+func (this *Scheduler) nextRun(externalId string) *time.Time {
+	this.mux.Lock()
+	reg, ok := this.registered[externalId]
+	this.mux.Unlock()
+	if !ok || !reg.scheduled() {
+		return nil
+	}
+	next := this.cron.Entry(reg.job).Next
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// runJob starts the process of entry for the firing planned at the given time
+// each run is traced in a new root span
+func (this *Scheduler) runJob(entry model.ScheduleEntry, planned time.Time) {
+	lag := time.Since(planned)
+	attributes := append(tracing.ScheduleAttributes(entry.Id, entry.User, entry.ProcessDeploymentId),
+		attribute.String("schedule.cron", entry.Cron),
+		attribute.Int64("schedule.version", entry.Version),
+		attribute.Float64("schedule.lag_seconds", lag.Seconds()))
+	if entry.Rrule != "" {
+		attributes = append(attributes, attribute.String("schedule.rrule", entry.Rrule))
+	}
+	if entry.Solar != nil {
+		attributes = append(attributes, attribute.String("schedule.solar.event", entry.Solar.Event), attribute.String("schedule.solar.offset", entry.Solar.Offset))
+	}
+	if entry.Interval != nil {
+		attributes = append(attributes, attribute.String("schedule.interval.every", entry.Interval.Every), attribute.String("schedule.interval.anchor", entry.Interval.Anchor.Format(time.RFC3339)))
+	}
+	if entry.ProcessAlias != nil {
+		attributes = append(attributes, attribute.String("schedule.process_alias", *entry.ProcessAlias))
+	}
+	ctx, span := this.tracing.Start(this.executionCtx, "scheduler.run", trace.WithNewRoot(), trace.WithAttributes(attributes...))
+	defer span.End()
+	executionId := uuid.New().String()
+	ctx = logging.WithExecutionId(logging.WithScheduleId(logging.WithUser(ctx, entry.User), entry.Id), executionId)
+
+	err := this.persistence.SetFired(ctx, entry.Id, entry.User, planned, this.nextRun(entry.Id))
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to store next run", "error", err)
+	}
+	delay, ok := this.delayFiring(ctx, span, entry)
+	if !ok {
+		this.recordSkipped(ctx, span, entry, executionId, "service stopped during the firing delay", delay)
+		return
+	}
+	if delay > 0 && !this.isRegistered(entry) {
+		slog.DebugContext(ctx, "drop delayed firing of changed entry")
+		return
+	}
+	if window, windowEnd, blocked := this.activeBlackout(time.Now()); blocked {
+		this.suppress(ctx, span, entry, executionId, window, windowEnd, delay)
+		return
+	}
+	reason, err := this.calendarSkipReason(ctx, entry, planned)
+	if err != nil {
+		this.recordCalendarError(ctx, span, entry, executionId, err, lag, delay)
+		return
+	}
+	if reason != "" {
+		this.recordSkipped(ctx, span, entry, executionId, reason, delay)
+		slog.DebugContext(ctx, "execution skipped by calendar", "reason", reason)
+		return
+	}
+	waited, throttled, ok := this.throttle(ctx, span, entry, executionId, delay)
+	if !ok {
+		return
+	}
+	if waited > 0 && !this.isRegistered(entry) {
+		slog.DebugContext(ctx, "drop throttled firing of changed entry")
+		return
+	}
+	release, queued, ok := this.awaitWorker(ctx, span, entry, executionId, delay+waited)
+	if !ok {
+		return
+	}
+	if queued > 0 && !this.isRegistered(entry) {
+		release()
+		slog.DebugContext(ctx, "drop queued firing of changed entry")
+		return
+	}
+	execution := model.Execution{
+		Id:         executionId,
+		ScheduleId: entry.Id,
+		User:       entry.User,
+		Delay:      formatDelay(delay + waited + queued),
+		Throttled:  throttled,
+		StartedAt:  time.Now(),
+	}
+	statusCode, err := this.processes.Execute(ctx, entry)
+	release()
+	execution.FinishedAt = time.Now()
+	execution.StatusCode = statusCode
+	span.SetAttributes(attribute.String("execution.id", execution.Id), attribute.Int("execution.status_code", statusCode))
+	if err != nil {
+		execution.Status = model.ExecutionStatusFailed
+		execution.Error = err.Error()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		execution.Status = model.ExecutionStatusSuccess
+	}
+	this.metrics.ObserveExecution(execution.Status, statusCode, execution.FinishedAt.Sub(execution.StartedAt), lag)
+	err = this.persistence.AddExecution(ctx, execution)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to store execution", "error", err)
+	}
+	slog.DebugContext(ctx, "executed schedule", "status", execution.Status, "status_code", statusCode, "lag", lag)
+}
+
+// recordSkipped records a firing that has been suppressed without calling the process engine
+func (this *Scheduler) recordSkipped(ctx context.Context, span trace.Span, entry model.ScheduleEntry, executionId string, reason string, delay time.Duration) {
+	now := time.Now()
+	execution := model.Execution{
+		Id:         executionId,
+		ScheduleId: entry.Id,
+		User:       entry.User,
+		Status:     model.ExecutionStatusSkipped,
+		Reason:     reason,
+		Delay:      formatDelay(delay),
+		StartedAt:  now,
+		FinishedAt: now,
+	}
+	span.SetAttributes(attribute.String("execution.id", execution.Id), attribute.String("execution.status", execution.Status), attribute.String("execution.reason", reason))
+	this.metrics.ObserveSkippedExecution()
+	err := this.persistence.AddExecution(ctx, execution)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to store execution", "error", err)
+	}
+}
+
+// recordCalendarError records a firing as failed because its calendars could not be loaded;
+// the process is not started, as it is unknown whether the day is excluded
+func (this *Scheduler) recordCalendarError(ctx context.Context, span trace.Span, entry model.ScheduleEntry, executionId string, err error, lag time.Duration, delay time.Duration) {
+	now := time.Now()
+	execution := model.Execution{
+		Id:         executionId,
+		ScheduleId: entry.Id,
+		User:       entry.User,
+		Status:     model.ExecutionStatusFailed,
+		Error:      "unable to load calendars: " + err.Error(),
+		Delay:      formatDelay(delay),
+		StartedAt:  now,
+		FinishedAt: now,
+	}
+	span.SetAttributes(attribute.String("execution.id", execution.Id))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	slog.ErrorContext(ctx, "unable to load calendars", "error", err)
+	this.metrics.ObserveExecution(execution.Status, 0, 0, lag)
+	err = this.persistence.AddExecution(ctx, execution)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to store execution", "error", err)
+	}
+}
+
+// startSpan starts the span of a scheduler operation and adds id and user to the log context;
+// the returned function ends the span with the status of err
+func (this *Scheduler) startSpan(ctx context.Context, name string, id string, user string) (context.Context, func(err error)) {
+	ctx = logging.WithScheduleId(logging.WithUser(ctx, user), id)
+	ctx, span := this.tracing.Start(ctx, name, trace.WithAttributes(attribute.String("schedule.id", id), attribute.String("schedule.user", user)))
+	return ctx, func(err error) {
+		tracing.End(span, err)
+	}
+}
+
+func getShutdownGracePeriod(config configuration.Config) (time.Duration, error) {
+	if config == nil || config.ShutdownGracePeriod == "" {
+		return DEFAULT_SHUTDOWN_GRACE_PERIOD, nil
+	}
+	result, err := time.ParseDuration(config.ShutdownGracePeriod)
+	if err != nil {
+		return result, fmt.Errorf("invalid shutdown_grace_period: %w", err)
+	}
+	return result, nil
+}
+
+// checkSchedule returns the parse error of the schedule of enabled entries, of the jitter and of the priority
+func checkSchedule(entry model.ScheduleEntry) error {
+	_, err := entry.GetJitter()
+	if err != nil {
+		return err
+	}
+	err = entry.ValidatePriority()
+	if err != nil {
+		return err
+	}
+	if entry.IsDisabled() {
+		return nil
+	}
+	_, err = entry.Schedule()
+	return err
+}
+
+func equalTimePtr(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
 }
 
 func getErrCode(err error) int {
@@ -161,6 +741,36 @@ func getErrCode(err error) int {
 	if err == model.ErrorAccessDenied {
 		return http.StatusNotFound
 	}
+	if err == model.ErrorVersionConflict {
+		return http.StatusPreconditionFailed
+	}
+	if err == model.ErrorDuplicateId {
+		return http.StatusConflict
+	}
+	if err == model.ErrorShuttingDown {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, model.ErrorUnknownCalendar) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, model.ErrorIntervalTooShort) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, model.ErrorQuotaExceeded) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, model.ErrorQuotaForbidden) {
+		return http.StatusForbidden
+	}
+	if err == model.ErrorQuotaLocked {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, model.ErrorPriorityForbidden) {
+		return http.StatusForbidden
+	}
+	if err == model.ErrorCalendarInUse {
+		return http.StatusConflict
+	}
 	if err != nil {
 		return http.StatusInternalServerError
 	}
@@ -0,0 +1,228 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// run with -race to detect unsynchronized access to the scheduler state
+func TestConcurrentChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	persistence := newMemoryPersistence()
+	s := New(&configuration.ConfigStruct{ShutdownGracePeriod: "1s"}, persistence, newProcessMock(), metrics.New(), nil)
+	err := s.Start(ctx, wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	const entryCount = 20
+	const workers = 8
+	ids := make([]string, entryCount)
+	for i := range ids {
+		entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "d" + strconv.Itoa(i)}, "user")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ids[i] = entry.Id
+	}
+
+	hammer := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		hammer.Add(1)
+		go func(w int) {
+			defer hammer.Done()
+			for i, id := range ids {
+				switch (i + w) % 6 {
+				case 0:
+					_, err, code := s.Update(ctx, model.ScheduleEntry{Id: id, Cron: "*/2 * * * * *", ProcessDeploymentId: "u"}, "user", nil)
+					expectCode(t, err, code, http.StatusOK, http.StatusNotFound)
+				case 1:
+					_, err, code := s.Patch(ctx, id, "user", []byte(`{"disabled":true}`), nil)
+					expectCode(t, err, code, http.StatusOK, http.StatusNotFound)
+				case 2:
+					_, err, code := s.Patch(ctx, id, "user", []byte(`{"disabled":false}`), nil)
+					expectCode(t, err, code, http.StatusOK, http.StatusNotFound)
+				case 3:
+					_, err, code := s.Get(ctx, id, "user")
+					expectCode(t, err, code, http.StatusOK, http.StatusNotFound)
+				case 4:
+					_, _, err, code := s.List(ctx, "user", model.ListOptions{})
+					expectCode(t, err, code, http.StatusOK)
+				case 5:
+					if w%2 == 0 {
+						err, code := s.Delete(ctx, id, "user")
+						expectCode(t, err, code, http.StatusOK)
+					} else {
+						_, err, code := s.Bulk(ctx, model.BulkRequest{Operations: []model.BulkOperation{
+							{Op: model.BulkOpDisable, Id: id},
+							{Op: model.BulkOpCreate, Entry: &model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "b"}},
+						}}, "user")
+						expectCode(t, err, code, http.StatusOK)
+					}
+				}
+			}
+		}(w)
+	}
+	hammer.Wait()
+	checkRegistry(t, s, persistence)
+}
+
+// update and delete of the same entry must not interleave; an interleaving could leave a job of a deleted entry behind
+func TestConcurrentUpdateAndDelete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	persistence := newMemoryPersistence()
+	s := New(&configuration.ConfigStruct{ShutdownGracePeriod: "1s"}, persistence, newProcessMock(), nil, nil)
+	err := s.Start(ctx, wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 50; i++ {
+		entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d"}, "user")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round := sync.WaitGroup{}
+		round.Add(2)
+		go func() {
+			defer round.Done()
+			_, err, code := s.Update(ctx, model.ScheduleEntry{Id: entry.Id, Cron: "30 * * * *", ProcessDeploymentId: "u"}, "user", nil)
+			expectCode(t, err, code, http.StatusOK, http.StatusNotFound)
+		}()
+		go func() {
+			defer round.Done()
+			err, code := s.Delete(ctx, entry.Id, "user")
+			expectCode(t, err, code, http.StatusOK)
+		}()
+		round.Wait()
+	}
+	checkRegistry(t, s, persistence)
+	if len(s.registered) != 0 || len(s.cron.Entries()) != 0 {
+		t.Error("unexpected remaining jobs", len(s.registered), len(s.cron.Entries()))
+	}
+}
+
+// a rolled back bulk delete restores the entry together with its execution history
+func TestBulkDeleteExecutionHistory(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddExecution(ctx, model.Execution{Id: "e1", ScheduleId: entry.Id, User: "user", Status: model.ExecutionStatusSuccess})
+
+	_, err, code := s.Bulk(ctx, model.BulkRequest{Atomic: true, Operations: []model.BulkOperation{
+		{Op: model.BulkOpDelete, Id: entry.Id},
+		{Op: model.BulkOpEnable, Id: "unknown"},
+	}}, "user")
+	expectCode(t, err, code, http.StatusNotFound)
+	if executions := p.executionsOf(entry.Id); len(executions) != 1 {
+		t.Error("execution history not restored", executions)
+	}
+
+	result, err, code := s.Bulk(ctx, model.BulkRequest{Operations: []model.BulkOperation{
+		{Op: model.BulkOpDelete, Id: entry.Id},
+	}}, "user")
+	expectCode(t, err, code, http.StatusOK)
+	if !result.Applied {
+		t.Error(result)
+	}
+	if executions := p.executionsOf(entry.Id); len(executions) != 0 {
+		t.Error("execution history not removed", executions)
+	}
+	checkRegistry(t, s, p)
+}
+
+// a rolled back bulk update puts the previous entry back including its version
+func TestBulkUpdateRollback(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "d", Tags: []string{"a"}}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err, code := s.Bulk(ctx, model.BulkRequest{Atomic: true, Operations: []model.BulkOperation{
+		{Op: model.BulkOpUpdate, Id: entry.Id, Entry: &model.ScheduleEntry{Cron: "30 * * * *", ProcessDeploymentId: "d"}},
+		{Op: model.BulkOpDisable, Id: entry.Id},
+		{Op: model.BulkOpEnable, Id: "unknown"},
+	}}, "user")
+	expectCode(t, err, code, http.StatusNotFound)
+	stored, err := p.Get(ctx, entry.Id, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Version != entry.Version || stored.Cron != entry.Cron || len(stored.Tags) != 1 || stored.IsDisabled() {
+		t.Error("entry not restored", stored)
+	}
+	checkRegistry(t, s, p)
+}
+
+func expectCode(t *testing.T, err error, code int, expected ...int) {
+	for _, e := range expected {
+		if code == e {
+			return
+		}
+	}
+	t.Error("unexpected result", code, err)
+}
+
+// checkRegistry checks that the cron registry matches the stored entries
+func checkRegistry(t *testing.T, s *Scheduler, persistence *memoryPersistence) {
+	t.Helper()
+	entries, err := persistence.GetAll(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	scheduled := 0
+	for _, entry := range entries {
+		if !s.isRegistered(entry) {
+			t.Error("entry not registered", entry.Id, entry.Version, entry.IsDisabled())
+		}
+		if entry.IsScheduled() {
+			scheduled++
+		}
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if len(s.registered) != len(entries) || len(s.cron.Entries()) != scheduled {
+		t.Error("unexpected job count", len(s.registered), len(s.cron.Entries()), len(entries), scheduled)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRruleTrigger(t *testing.T) {
+	s, _, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+
+	t.Run("invalid rrule", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Rrule: "RRULE:FREQ=SECONDLY", ProcessDeploymentId: "d"}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+
+	start := time.Now().UTC().Truncate(time.Second)
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{
+		Rrule:               "DTSTART:" + start.Format("20060102T150405Z") + "\nRRULE:FREQ=SECONDLY;COUNT=3",
+		ProcessDeploymentId: "d",
+	}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("next run", func(t *testing.T) {
+		info, err, _ := s.Get(ctx, entry.Id, "user")
+		if err != nil || info.NextRun == nil || info.NextRun.After(start.Add(2*time.Second)) {
+			t.Error(err, info.NextRun)
+		}
+	})
+	t.Run("preview ends with count", func(t *testing.T) {
+		runs, err, _ := s.NextRuns(ctx, entry.Id, "user", 10, false)
+		if err != nil || len(runs) > 2 {
+			t.Error(err, runs)
+		}
+	})
+	t.Run("executions", func(t *testing.T) {
+		time.Sleep(3 * time.Second)
+		if count := process.count(entry.Id); count < 1 || count > 2 {
+			t.Error("unexpected execution count", count)
+		}
+		info, err, _ := s.Get(ctx, entry.Id, "user")
+		if err != nil || info.NextRun != nil {
+			t.Error("expected no further run", err, info.NextRun)
+		}
+	})
+}
+
+func TestSolarTrigger(t *testing.T) {
+	s, _, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+
+	t.Run("invalid position", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Solar: &model.SolarTrigger{Event: model.SolarEventSunset, Latitude: 100}, ProcessDeploymentId: "d"}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{
+		Solar:               &model.SolarTrigger{Event: model.SolarEventSunset, Latitude: 52.52, Longitude: 13.405, Offset: "30m"},
+		ProcessDeploymentId: "d",
+	}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("registered", func(t *testing.T) {
+		info, err, _ := s.Get(ctx, entry.Id, "user")
+		if err != nil || info.NextRun == nil || !info.NextRun.Equal(*entry.NextRun) {
+			t.Error(err, info.NextRun, entry.NextRun)
+		}
+	})
+	t.Run("preview", func(t *testing.T) {
+		runs, err, _ := s.NextRuns(ctx, entry.Id, "user", 3, false)
+		if err != nil || len(runs) != 3 {
+			t.Error(err, runs)
+			return
+		}
+		for i := 1; i < len(runs); i++ {
+			if gap := runs[i].Time.Sub(runs[i-1].Time); gap < 23*time.Hour || gap > 25*time.Hour {
+				t.Error(runs)
+			}
+		}
+	})
+}
+
+func TestIntervalTrigger(t *testing.T) {
+	s, p, stop := startTestScheduler(t, "0")
+	defer stop()
+	ctx := context.Background()
+	process := s.processes.(*processMock)
+
+	t.Run("invalid duration", func(t *testing.T) {
+		_, err, code := s.Add(ctx, model.ScheduleEntry{Interval: &model.IntervalTrigger{Every: "P1M", Anchor: time.Now()}, ProcessDeploymentId: "d"}, "user")
+		expectCode(t, err, code, http.StatusBadRequest)
+	})
+
+	anchor := time.Now().Add(-10500 * time.Millisecond)
+	entry, err, _ := s.Add(ctx, model.ScheduleEntry{
+		Interval:            &model.IntervalTrigger{Every: "PT1S", Anchor: anchor},
+		ProcessDeploymentId: "d",
+	}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("preview", func(t *testing.T) {
+		runs, err, _ := s.NextRuns(ctx, entry.Id, "user", 5, false)
+		if err != nil || len(runs) != 5 {
+			t.Error(err, runs)
+			return
+		}
+		for i, run := range runs {
+			if run.Time.Sub(anchor)%time.Second != 0 {
+				t.Error("not aligned to anchor", run.Time)
+			}
+			if i > 0 && run.Time.Sub(runs[i-1].Time) != time.Second {
+				t.Error(runs)
+			}
+		}
+	})
+	t.Run("executions", func(t *testing.T) {
+		time.Sleep(2500 * time.Millisecond)
+		if count := process.count(entry.Id); count < 1 || count > 3 {
+			t.Error("unexpected execution count", count)
+		}
+		stored, err := p.Get(ctx, entry.Id, "user")
+		if err != nil || stored.LastFiredAt == nil {
+			t.Error(err, stored.LastFiredAt)
+			return
+		}
+		if stored.LastFiredAt.Sub(anchor)%time.Second != 0 {
+			t.Error("last firing not aligned to anchor", stored.LastFiredAt)
+		}
+		if stored.NextRun == nil || !stored.NextRun.Equal(stored.LastFiredAt.Add(time.Second)) {
+			t.Error(stored.NextRun, stored.LastFiredAt)
+		}
+	})
+	t.Run("update keeps last firing", func(t *testing.T) {
+		before, err := p.Get(ctx, entry.Id, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		before.Interval = &model.IntervalTrigger{Every: "PT1H", Anchor: anchor}
+		before.LastFiredAt = nil
+		updated, err, _ := s.Update(ctx, before, "user", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, err := p.Get(ctx, entry.Id, "user")
+		if err != nil || stored.LastFiredAt == nil {
+			t.Error(err, stored.LastFiredAt)
+		}
+		if updated.NextRun == nil || !updated.NextRun.Equal(anchor.Add(time.Hour)) {
+			t.Error(updated.NextRun)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// robfig/cron starts a goroutine for every firing; the worker pool bounds how many of them start a process at the same time.
+// Firings that find no free worker wait in a queue and are started by the priority of their entry and, within a priority, in order of arrival.
+// If the queue is full, the newest waiting firing of a lower class is evicted to make room;
+// if there is none, the new firing is dropped. Dropped firings are recorded with model.ExecutionStatusThrottled.
+// A low priority firing that arrives while an earlier firing of the same entry is still waiting is coalesced with it:
+// the waiting firing keeps its place and the new one is recorded as skipped.
+
+var errQueueFull = errors.New("execution queue full")
+var errPoolStopped = errors.New("service stopped while waiting for a worker")
+var errCoalesced = errors.New("coalesced with a waiting firing of the same entry")
+
+type workerPool struct {
+	size     int
+	maxQueue int //0 is unlimited
+	metrics  *metrics.Metrics
+	mux      sync.Mutex
+	busy     int
+	queues   [][]*poolTicket //waiting firings by model.PriorityRank
+}
+
+type poolTicket struct {
+	priority string
+	key      string        //identifies the entry version of the firing; empty if the firing may not be coalesced
+	ready    chan struct{} //closed when the ticket is granted a worker or evicted
+	granted  bool
+	evicted  bool
+}
+
+// newWorkerPool returns nil if the pool is disabled
+func newWorkerPool(config configuration.Config, m *metrics.Metrics) (*workerPool, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if config.WorkerPoolSize < 0 || config.WorkerQueueSize < 0 {
+		return nil, fmt.Errorf("invalid worker_pool_size or worker_queue_size: expect a positive number or 0")
+	}
+	if config.WorkerPoolSize == 0 {
+		return nil, nil
+	}
+	result := &workerPool{
+		size:     int(config.WorkerPoolSize),
+		maxQueue: int(config.WorkerQueueSize),
+		metrics:  m,
+		queues:   make([][]*poolTicket, len(model.Priorities)),
+	}
+	result.updateMetrics()
+	return result, nil
+}
+
+// acquire waits for a free worker; the returned release function must be called once the worker is no longer needed.
+// returns errQueueFull if the firing has been dropped or evicted, errCoalesced if a low priority firing with the same key
+// is already waiting and errPoolStopped if stop is done in the meantime.
+func (this *workerPool) acquire(stop context.Context, priority string, key string) (release func(), waited time.Duration, err error) {
+	if this == nil {
+		return func() {}, 0, nil
+	}
+	start := time.Now()
+	rank := model.PriorityRank(priority)
+	this.mux.Lock()
+	if this.busy < this.size {
+		this.busy++
+		this.updateMetrics()
+		this.mux.Unlock()
+		this.metrics.ObserveQueueWait(priority, 0)
+		return this.release, 0, nil
+	}
+	if priority == model.PriorityLow && this.waiting(rank, key) {
+		this.mux.Unlock()
+		this.metrics.ObserveQueueCoalesced(priority)
+		return nil, 0, errCoalesced
+	}
+	if this.maxQueue > 0 && this.queued() >= this.maxQueue && !this.evict(rank) {
+		this.mux.Unlock()
+		this.metrics.ObserveQueueDropped(priority)
+		return nil, 0, errQueueFull
+	}
+	ticket := &poolTicket{priority: priority, key: key, ready: make(chan struct{})}
+	this.queues[rank] = append(this.queues[rank], ticket)
+	this.updateMetrics()
+	this.mux.Unlock()
+
+	select {
+	case <-ticket.ready:
+	case <-stop.Done():
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	waited = time.Since(start)
+	switch {
+	case ticket.evicted:
+		return nil, waited, errQueueFull
+	case ticket.granted && stop.Err() == nil:
+		this.metrics.ObserveQueueWait(priority, waited)
+		return this.release, waited, nil
+	case ticket.granted:
+		this.releaseUnsafe()
+	default:
+		this.remove(rank, ticket)
+	}
+	return nil, waited, errPoolStopped
+}
+
+func (this *workerPool) release() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.releaseUnsafe()
+}
+
+// releaseUnsafe passes the worker to the next waiting firing; expects this.mux to be locked
+func (this *workerPool) releaseUnsafe() {
+	this.busy--
+	for rank, queue := range this.queues {
+		if len(queue) > 0 {
+			ticket := queue[0]
+			this.queues[rank] = queue[1:]
+			ticket.granted = true
+			close(ticket.ready)
+			this.busy++
+			break
+		}
+	}
+	this.updateMetrics()
+}
+
+// evict drops the newest waiting firing of the lowest class below rank; expects this.mux to be locked
+func (this *workerPool) evict(rank int) bool {
+	for r := len(this.queues) - 1; r > rank; r-- {
+		queue := this.queues[r]
+		if len(queue) > 0 {
+			ticket := queue[len(queue)-1]
+			this.queues[r] = queue[:len(queue)-1]
+			ticket.evicted = true
+			close(ticket.ready)
+			this.metrics.ObserveQueueDropped(ticket.priority)
+			return true
+		}
+	}
+	return false
+}
+
+// remove expects this.mux to be locked
+func (this *workerPool) remove(rank int, ticket *poolTicket) {
+	queue := this.queues[rank]
+	for i, t := range queue {
+		if t == ticket {
+			this.queues[rank] = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	this.updateMetrics()
+}
+
+// waiting checks if a firing with key waits in the queue of rank; expects this.mux to be locked
+func (this *workerPool) waiting(rank int, key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, ticket := range this.queues[rank] {
+		if ticket.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// queued expects this.mux to be locked
+func (this *workerPool) queued() (result int) {
+	for _, queue := range this.queues {
+		result += len(queue)
+	}
+	return result
+}
+
+// updateMetrics expects this.mux to be locked
+func (this *workerPool) updateMetrics() {
+	this.metrics.SetWorkersBusy(this.busy)
+	for rank, queue := range this.queues {
+		this.metrics.SetQueueDepth(model.Priorities[rank], len(queue))
+	}
+}
+
+// awaitWorker waits for a worker of the pool to start the process of entry.
+// ok is false if the firing has been dropped and recorded; otherwise release must be called after the process start.
+func (this *Scheduler) awaitWorker(ctx context.Context, span trace.Span, entry model.ScheduleEntry, executionId string, delay time.Duration) (release func(), waited time.Duration, ok bool) {
+	priority := entry.GetPriority()
+	release, waited, err := this.workers.acquire(this.delayCtx, priority, entry.Id+"@"+strconv.FormatInt(entry.Version, 10))
+	if waited > 0 {
+		span.SetAttributes(attribute.String("execution.priority", priority), attribute.Float64("execution.queue_wait_seconds", waited.Seconds()))
+	}
+	switch err {
+	case nil:
+		return release, waited, true
+	case errQueueFull:
+		this.recordThrottled(ctx, span, entry, executionId, err.Error(), delay+waited)
+		slog.WarnContext(ctx, "execution dropped", "reason", err.Error(), "priority", priority)
+	default:
+		this.recordSkipped(ctx, span, entry, executionId, err.Error(), delay+waited)
+		slog.DebugContext(ctx, "execution skipped", "reason", err.Error(), "priority", priority)
+	}
+	return nil, waited, false
+}
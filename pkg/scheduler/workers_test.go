@@ -0,0 +1,221 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolPriority(t *testing.T) {
+	pool, err := newWorkerPool(&configuration.ConfigStruct{WorkerPoolSize: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, waited, err := pool.acquire(context.Background(), model.PriorityNormal, "")
+	if err != nil || waited != 0 {
+		t.Fatal(err, waited)
+	}
+	order := make(chan string, 3)
+	wg := sync.WaitGroup{}
+	for i, priority := range []string{model.PriorityLow, model.PriorityNormal, model.PriorityHigh} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, _, err := pool.acquire(context.Background(), priority, "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- priority
+			release()
+		}()
+		waitForQueued(t, pool, i+1)
+	}
+	release()
+	wg.Wait()
+	close(order)
+	result := []string{}
+	for priority := range order {
+		result = append(result, priority)
+	}
+	if len(result) != 3 || result[0] != model.PriorityHigh || result[1] != model.PriorityNormal || result[2] != model.PriorityLow {
+		t.Error(result)
+	}
+	if pool.busy != 0 || pool.queued() != 0 {
+		t.Error(pool.busy, pool.queued())
+	}
+}
+
+func TestWorkerPoolQueueFull(t *testing.T) {
+	pool, err := newWorkerPool(&configuration.ConfigStruct{WorkerPoolSize: 1, WorkerQueueSize: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, _, _ := pool.acquire(context.Background(), model.PriorityNormal, "")
+	results := make(chan error, 10)
+	queue := func(priority string, expectedQueued int) {
+		go func() {
+			release, _, err := pool.acquire(context.Background(), priority, "")
+			if err == nil {
+				release()
+			}
+			results <- err
+		}()
+		waitForQueued(t, pool, expectedQueued)
+	}
+	queue(model.PriorityLow, 1)
+	queue(model.PriorityLow, 2)
+	queue(model.PriorityNormal, 2)
+	if err := <-results; err != errQueueFull {
+		t.Error("expected the newest low priority firing to be evicted", err)
+	}
+	_, _, err = pool.acquire(context.Background(), model.PriorityLow, "")
+	if err != errQueueFull {
+		t.Error("expected dropped firing", err)
+	}
+	release()
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWorkerPoolStop(t *testing.T) {
+	pool, err := newWorkerPool(&configuration.ConfigStruct{WorkerPoolSize: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, _, _ := pool.acquire(context.Background(), model.PriorityNormal, "")
+	stop, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		_, _, err := pool.acquire(stop, model.PriorityNormal, "")
+		result <- err
+	}()
+	waitForQueued(t, pool, 1)
+	cancel()
+	if err := <-result; err != errPoolStopped {
+		t.Error(err)
+	}
+	release()
+	if pool.busy != 0 || pool.queued() != 0 {
+		t.Error(pool.busy, pool.queued())
+	}
+
+	none, err := newWorkerPool(&configuration.ConfigStruct{}, nil)
+	if err != nil || none != nil {
+		t.Error(err, none)
+	}
+	_, err = newWorkerPool(&configuration.ConfigStruct{WorkerPoolSize: -1}, nil)
+	if err == nil {
+		t.Error("expected invalid size error")
+	}
+}
+
+func TestWorkerPoolLimitsExecutions(t *testing.T) {
+	s, p, stop := startTestSchedulerWithConfig(t, &configuration.ConfigStruct{ReconcileInterval: "0", ShutdownGracePeriod: "1s", WorkerPoolSize: 1, WorkerQueueSize: 1})
+	defer stop()
+	process := newBlockingProcess()
+	s.processes = process
+	entry, err, _ := s.Add(context.Background(), model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "d"}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := sync.WaitGroup{}
+	run := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runJob(entry, time.Now())
+		}()
+	}
+	run()
+	<-process.started
+	run()
+	waitForQueued(t, s.workers, 1)
+	s.runJob(entry, time.Now())
+	executions := p.executionsOf(entry.Id)
+	if len(executions) != 1 || executions[0].Status != model.ExecutionStatusThrottled || executions[0].Reason != errQueueFull.Error() {
+		t.Error("expected dropped firing", executions)
+	}
+	close(process.release)
+	wg.Wait()
+	executions = p.executionsOf(entry.Id)
+	if process.count(entry.Id) != 2 || len(executions) != 3 {
+		t.Error(process.count(entry.Id), executions)
+	}
+	queued := 0
+	for _, execution := range executions {
+		if execution.Status == model.ExecutionStatusSuccess && execution.Delay != "" {
+			queued++
+		}
+	}
+	if queued != 1 {
+		t.Error("expected one queued execution", executions)
+	}
+}
+
+func waitForQueued(t *testing.T, pool *workerPool, expected int) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		pool.mux.Lock()
+		queued := pool.queued()
+		pool.mux.Unlock()
+		if queued == expected {
+			return
+		}
+	}
+	t.Fatal("timeout waiting for queued firings", expected)
+}
+
+func TestWorkerPoolCoalesce(t *testing.T) {
+	pool, err := newWorkerPool(&configuration.ConfigStruct{WorkerPoolSize: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, _, _ := pool.acquire(context.Background(), model.PriorityNormal, "a@1")
+	results := make(chan error, 10)
+	queue := func(priority string, key string, expectedQueued int) {
+		go func() {
+			release, _, err := pool.acquire(context.Background(), priority, key)
+			if err == nil {
+				release()
+			}
+			results <- err
+		}()
+		waitForQueued(t, pool, expectedQueued)
+	}
+	queue(model.PriorityLow, "b@1", 1)
+	queue(model.PriorityNormal, "c@1", 2)
+	queue(model.PriorityNormal, "c@1", 3)
+	queue(model.PriorityLow, "b@2", 4)
+	if _, _, err := pool.acquire(context.Background(), model.PriorityLow, "b@1"); err != errCoalesced {
+		t.Error("expected coalesced low priority firing", err)
+	}
+	release()
+	for i := 0; i < 4; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -21,25 +21,59 @@ import (
 	"github.com/SENERGY-Platform/process-scheduler/pkg/api"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/metrics"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/persistence"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
 	"sync"
 )
 
-//starts services and goroutines; returns a waiting group which is done as soon as all go routines are stopped
+// starts services and goroutines; returns a waiting group which is done as soon as all go routines are stopped.
+// When ctx is done, the components are stopped in order: the scheduler rejects changes, stops scheduling
+// and waits for running executions; then the api server is stopped; persistence and tracing are closed last.
 func Start(ctx context.Context, config configuration.Config) (wg *sync.WaitGroup, err error) {
 	wg = &sync.WaitGroup{}
-	db, err := persistence.New(ctx, wg, config)
+	infrastructureCtx, stopInfrastructure := context.WithCancel(context.Background())
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	apiCtx, stopApi := context.WithCancel(context.Background())
+	infrastructureWg, schedulerWg, apiWg := &sync.WaitGroup{}, &sync.WaitGroup{}, &sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+		case <-infrastructureCtx.Done(): //start failed
+		}
+		stopScheduler()
+		schedulerWg.Wait()
+		stopApi()
+		apiWg.Wait()
+		stopInfrastructure()
+		infrastructureWg.Wait()
+	}()
+	defer func() {
+		if err != nil {
+			stopInfrastructure()
+		}
+	}()
+
+	m := metrics.New()
+	t, err := tracing.New(infrastructureCtx, infrastructureWg, config)
+	if err != nil {
+		return wg, err
+	}
+	db, err := persistence.New(infrastructureCtx, infrastructureWg, config, m, t)
 	if err != nil {
 		return wg, err
 	}
-	process := processapi.New(config)
-	controller := scheduler.New(db, process)
-	err = controller.Start(ctx, wg)
+	process := processapi.New(config, t)
+	controller := scheduler.New(config, db, process, m, t)
+	err = controller.Start(schedulerCtx, schedulerWg)
 	if err != nil {
 		return wg, err
 	}
-	err = api.Start(ctx, wg, config, controller, util.NewJwt(config))
+	err = api.Start(apiCtx, apiWg, config, controller, util.NewJwt(config), m, t)
 	return
 }
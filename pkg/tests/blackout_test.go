@@ -0,0 +1,129 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"github.com/golang-jwt/jwt"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBlackoutApi(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	window := model.BlackoutWindow{Description: "sunday maintenance", Cron: "0 2 * * 0", Duration: "2h"}
+	t.Run("user is forbidden", blackoutRequest(config, false, "POST", "/admin/blackouts", window, http.StatusForbidden, nil))
+	t.Run("invalid window", blackoutRequest(config, true, "POST", "/admin/blackouts", model.BlackoutWindow{Cron: "0 2 * * 0"}, http.StatusBadRequest, nil))
+	t.Run("create", blackoutRequest(config, true, "POST", "/admin/blackouts", window, http.StatusOK, &window))
+	if window.Id == "" {
+		t.Error("missing id")
+		return
+	}
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	end := start.Add(time.Hour)
+	oneOff := model.BlackoutWindow{Description: "engine upgrade", Start: &start, End: &end}
+	t.Run("create one-off", blackoutRequest(config, true, "POST", "/admin/blackouts", oneOff, http.StatusOK, &oneOff))
+
+	window.Duration = "3h"
+	t.Run("update", blackoutRequest(config, true, "PUT", "/admin/blackouts/"+window.Id, window, http.StatusOK, nil))
+	t.Run("update with id mismatch", blackoutRequest(config, true, "PUT", "/admin/blackouts/unknown", window, http.StatusBadRequest, nil))
+
+	t.Run("list", func(t *testing.T) {
+		list := []model.BlackoutWindow{}
+		blackoutRequest(config, true, "GET", "/admin/blackouts", nil, http.StatusOK, &list)(t)
+		if len(list) != 2 || list[0].Id != window.Id || list[0].Duration != "3h" || list[0].CreatedBy != "admin" || list[1].End == nil || !list[1].End.Equal(end) {
+			t.Error(list)
+		}
+	})
+
+	t.Run("delete", blackoutRequest(config, true, "DELETE", "/admin/blackouts/"+window.Id, nil, http.StatusOK, nil))
+	t.Run("delete again", blackoutRequest(config, true, "DELETE", "/admin/blackouts/"+window.Id, nil, http.StatusNotFound, nil))
+}
+
+// blackoutRequest sends body as json and decodes the response into result (if not nil)
+func blackoutRequest(config configuration.Config, admin bool, method string, path string, body interface{}, expectedCode int, result interface{}) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		var reader io.Reader
+		if body != nil {
+			temp, err := json.Marshal(body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			reader = bytes.NewReader(temp)
+		}
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if admin {
+			err = setAdminAuthToken(req, "admin")
+		} else {
+			err = processapi.SetAuthToken(req, "user1")
+		}
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			temp, _ := io.ReadAll(resp.Body)
+			t.Error(resp.StatusCode, expectedCode, string(temp))
+			return
+		}
+		if result != nil {
+			err = json.NewDecoder(resp.Body).Decode(result)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func setAdminAuthToken(req *http.Request, user string) error {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":          user,
+		"realm_access": map[string]interface{}{"roles": []string{"admin", "user"}},
+	})
+	signed, err := token.SignedString([]byte("test"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+	return nil
+}
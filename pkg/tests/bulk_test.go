@@ -0,0 +1,213 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBulk(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	bTrue := true
+	id1 := ""
+	id2 := ""
+
+	t.Run("create non atomic", bulkRequest(config, "user1", model.BulkRequest{
+		Operations: []model.BulkOperation{
+			{Op: model.BulkOpCreate, Entry: &model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1"}},
+			{Op: model.BulkOpCreate, Entry: &model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-2"}},
+			{Op: model.BulkOpCreate, Entry: &model.ScheduleEntry{Cron: "* * * * *"}},
+		},
+	}, http.StatusOK, func(t *testing.T, response model.BulkResponse) {
+		expectBulkStatus(t, response, true, http.StatusOK, http.StatusOK, http.StatusBadRequest)
+		if len(response.Results) == 3 {
+			id1 = response.Results[0].Id
+			id2 = response.Results[1].Id
+		}
+	}))
+
+	t.Run("atomic with invalid operation", bulkRequest(config, "user1", model.BulkRequest{
+		Atomic: true,
+		Operations: []model.BulkOperation{
+			{Op: model.BulkOpDisable, Id: id1},
+			{Op: "foo", Id: id2},
+		},
+	}, http.StatusBadRequest, func(t *testing.T, response model.BulkResponse) {
+		expectBulkStatus(t, response, false, http.StatusFailedDependency, http.StatusBadRequest)
+	}))
+
+	t.Run("atomic with failing operation", bulkRequest(config, "user1", model.BulkRequest{
+		Atomic: true,
+		Operations: []model.BulkOperation{
+			{Op: model.BulkOpDisable, Id: id1},
+			{Op: model.BulkOpDelete, Id: id2},
+			{Op: model.BulkOpCreate, Entry: &model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-3"}},
+			{Op: model.BulkOpEnable, Id: "unknown"},
+			{Op: model.BulkOpDelete, Id: id1},
+		},
+	}, http.StatusNotFound, func(t *testing.T, response model.BulkResponse) {
+		expectBulkStatus(t, response, false, http.StatusFailedDependency, http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency)
+	}))
+
+	t.Run("check rollback", listSchedulesIgnoreVersion(config, "user1", []model.ScheduleEntry{
+		{Cron: "* * * * *", ProcessDeploymentId: "deployment-1"},
+		{Cron: "* * * * *", ProcessDeploymentId: "deployment-2"},
+	}, &id1, &id2))
+
+	t.Run("atomic with version conflict", bulkRequest(config, "user1", model.BulkRequest{
+		Atomic: true,
+		Operations: []model.BulkOperation{
+			{Op: model.BulkOpDisable, Id: id1},
+			{Op: model.BulkOpDisable, Id: id2, Version: func() *int64 { v := int64(42); return &v }()},
+		},
+	}, http.StatusPreconditionFailed, func(t *testing.T, response model.BulkResponse) {
+		expectBulkStatus(t, response, false, http.StatusFailedDependency, http.StatusPreconditionFailed)
+	}))
+
+	t.Run("atomic success", bulkRequest(config, "user1", model.BulkRequest{
+		Atomic: true,
+		Operations: []model.BulkOperation{
+			{Op: model.BulkOpDisable, Id: id1},
+			{Op: model.BulkOpUpdate, Id: id2, Entry: &model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "deployment-4"}},
+		},
+	}, http.StatusOK, func(t *testing.T, response model.BulkResponse) {
+		expectBulkStatus(t, response, true, http.StatusOK, http.StatusOK)
+	}))
+
+	t.Run("check success", listSchedulesIgnoreVersion(config, "user1", []model.ScheduleEntry{
+		{Cron: "* * * * *", ProcessDeploymentId: "deployment-1", Disabled: &bTrue},
+		{Cron: "0 * * * *", ProcessDeploymentId: "deployment-4"},
+	}, &id1, &id2))
+
+	t.Run("delete", bulkRequest(config, "user1", model.BulkRequest{
+		Operations: []model.BulkOperation{
+			{Op: model.BulkOpDelete, Id: id1},
+			{Op: model.BulkOpDelete, Id: id2},
+		},
+	}, http.StatusOK, func(t *testing.T, response model.BulkResponse) {
+		expectBulkStatus(t, response, true, http.StatusOK, http.StatusOK)
+	}))
+	t.Run("check deleted", listScheduleIds(config, "user1", "", 0, &[]string{}))
+}
+
+func expectBulkStatus(t *testing.T, response model.BulkResponse, applied bool, status ...int) {
+	if response.Applied != applied {
+		t.Error("unexpected applied", response.Applied, applied)
+	}
+	if len(response.Results) != len(status) {
+		t.Error("unexpected result count", len(response.Results), len(status))
+		return
+	}
+	for i, result := range response.Results {
+		if result.Index != i || result.Status != status[i] {
+			t.Error("unexpected result", i, result, status[i])
+		}
+	}
+}
+
+// listSchedulesIgnoreVersion compares the listed entries with expected; the expected ids are replaced by the given id references
+func listSchedulesIgnoreVersion(config configuration.Config, userId string, expected []model.ScheduleEntry, ids ...*string) func(t *testing.T) {
+	return func(t *testing.T) {
+		resp, err := listSchedulesRequest(config, userId, "")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		result := []model.ScheduleEntry{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(result) != len(expected) {
+			t.Error(len(result), len(expected))
+			return
+		}
+		byId := map[string]model.ScheduleEntry{}
+		for _, entry := range result {
+			byId[entry.Id] = entry
+		}
+		for i, e := range expected {
+			actual, ok := byId[*ids[i]]
+			if !ok {
+				t.Error("missing", *ids[i])
+				continue
+			}
+			if actual.Cron != e.Cron || actual.ProcessDeploymentId != e.ProcessDeploymentId || actual.IsDisabled() != e.IsDisabled() {
+				t.Error(actual, e)
+			}
+		}
+	}
+}
+
+func bulkRequest(config configuration.Config, userId string, bulk model.BulkRequest, expectedCode int, check func(t *testing.T, response model.BulkResponse)) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		path := "/schedules/bulk"
+		method := "POST"
+		buf := bytes.NewBuffer([]byte{})
+		err := json.NewEncoder(buf).Encode(bulk)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			t.Error(resp.StatusCode, expectedCode, buf.String())
+			return
+		}
+		response := model.BulkResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		check(t, response)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarApi(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"X-WR-CALNAME:holidays",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:" + tomorrow.Format("20060102"),
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	vacation, _ := json.Marshal(model.Calendar{Dates: []string{"2030-08-01", "2030-08-02"}})
+
+	t.Run("import ics", authRequest(config, "user1", "PUT", "/calendars/holidays", "text/calendar", []byte(ics), http.StatusOK, nil))
+	t.Run("invalid ics", authRequest(config, "user1", "PUT", "/calendars/invalid", "text/calendar", []byte("foo"), http.StatusBadRequest, nil))
+	t.Run("set date list", authRequest(config, "user1", "PUT", "/calendars/vacation", "application/json", vacation, http.StatusOK, nil))
+	t.Run("list", func(t *testing.T) {
+		list := []model.Calendar{}
+		authRequest(config, "user1", "GET", "/calendars", "", nil, http.StatusOK, &list)(t)
+		if len(list) != 2 || list[0].Name != "holidays" || len(list[0].Dates) != 1 || list[0].Dates[0] != tomorrow.Format(model.CalendarDateFormat) || list[1].Name != "vacation" {
+			t.Error(list)
+		}
+	})
+
+	unknown, _ := json.Marshal(model.ScheduleEntry{Cron: "0 12 * * *", ProcessDeploymentId: "d", ExcludeCalendars: []string{"unknown"}})
+	t.Run("unknown reference", authRequest(config, "user1", "POST", "/schedules", "application/json", unknown, http.StatusBadRequest, nil))
+
+	id := ""
+	t.Run("create", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "0 12 * * *", ProcessDeploymentId: "d", ExcludeCalendars: []string{"holidays", "vacation"}}, &id))
+
+	t.Run("next runs", func(t *testing.T) {
+		runs := []model.PlannedRun{}
+		authRequest(config, "user1", "GET", "/schedules/"+id+"/next?count=3", "", nil, http.StatusOK, &runs)(t)
+		if len(runs) != 3 {
+			t.Error(runs)
+		}
+		for _, run := range runs {
+			if run.Skipped || run.Time.Format(model.CalendarDateFormat) == tomorrow.Format(model.CalendarDateFormat) {
+				t.Error(run)
+			}
+		}
+	})
+	t.Run("next runs with skipped", func(t *testing.T) {
+		runs := []model.PlannedRun{}
+		authRequest(config, "user1", "GET", "/schedules/"+id+"/next?count=3&include_skipped=true", "", nil, http.StatusOK, &runs)(t)
+		if len(runs) != 4 {
+			t.Error(runs)
+			return
+		}
+		found := false
+		for _, run := range runs {
+			if run.Skipped {
+				found = run.Reason == "excluded by calendar holidays" && run.Time.Format(model.CalendarDateFormat) == tomorrow.Format(model.CalendarDateFormat)
+			}
+		}
+		if !found {
+			t.Error(runs)
+		}
+	})
+	t.Run("invalid count", authRequest(config, "user1", "GET", "/schedules/"+id+"/next?count=0", "", nil, http.StatusBadRequest, nil))
+
+	t.Run("delete referenced", authRequest(config, "user1", "DELETE", "/calendars/holidays", "", nil, http.StatusConflict, nil))
+	t.Run("delete unreferenced", func(t *testing.T) {
+		authRequest(config, "user1", "DELETE", "/schedules/"+id, "", nil, http.StatusOK, nil)(t)
+		authRequest(config, "user1", "DELETE", "/calendars/holidays", "", nil, http.StatusOK, nil)(t)
+		authRequest(config, "user1", "GET", "/calendars/holidays", "", nil, http.StatusNotFound, nil)(t)
+	})
+}
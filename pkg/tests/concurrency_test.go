@@ -0,0 +1,159 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentRequests sends conflicting requests for the same entries in parallel;
+// run with -race to detect unsynchronized access to the scheduler state
+func TestConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	const entryCount = 10
+	const workers = 6
+	ids := make([]string, entryCount)
+	for i := range ids {
+		t.Run("create "+strconv.Itoa(i), createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment"}, &ids[i]))
+	}
+
+	hammer := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		hammer.Add(1)
+		go func(w int) {
+			defer hammer.Done()
+			for i, id := range ids {
+				path := "/schedules/" + url.PathEscape(id)
+				var code int
+				var err error
+				switch (i + w) % 5 {
+				case 0:
+					code, err = concurrentRequest(config, "user1", "PUT", path, "application/json", `{"id":"`+id+`","cron":"0 * * * *","process_deployment_id":"updated"}`)
+				case 1:
+					code, err = concurrentRequest(config, "user1", "PATCH", path, "application/merge-patch+json", `{"disabled":true}`)
+				case 2:
+					code, err = concurrentRequest(config, "user1", "PATCH", path, "application/merge-patch+json", `{"disabled":false}`)
+				case 3:
+					code, err = concurrentRequest(config, "user1", "GET", path, "", "")
+				case 4:
+					code, err = concurrentRequest(config, "user1", "DELETE", path, "", "")
+				}
+				if err != nil {
+					t.Error(err)
+					continue
+				}
+				if code != http.StatusOK && code != http.StatusNotFound {
+					t.Error("unexpected status", code)
+				}
+			}
+		}(w)
+	}
+	hammer.Wait()
+
+	t.Run("check job metrics", func(t *testing.T) {
+		resp, err := listSchedulesRequest(config, "user1", "")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		entries := []model.ScheduleEntry{}
+		err = json.NewDecoder(resp.Body).Decode(&entries)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		enabled := 0
+		for _, entry := range entries {
+			if !entry.IsDisabled() {
+				enabled++
+			}
+		}
+		jobs, err := getJobMetrics(config)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if jobs["total"] != len(entries) || jobs["enabled"] != enabled {
+			t.Error(jobs, len(entries), enabled)
+		}
+	})
+}
+
+func concurrentRequest(config configuration.Config, userId string, method string, path string, contentType string, body string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost:"+config.ApiPort+path, bytes.NewBufferString(body))
+	if err != nil {
+		return 0, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	err = processapi.SetAuthToken(req, userId)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// getJobMetrics returns the process_scheduler_jobs gauge by state
+func getJobMetrics(config configuration.Config) (result map[string]int, err error) {
+	resp, err := http.Get("http://localhost:" + config.ApiPort + "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result = map[string]int{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "process_scheduler_jobs{") {
+			continue
+		}
+		labels, value, _ := strings.Cut(strings.TrimPrefix(line, "process_scheduler_jobs{"), "} ")
+		state := strings.Trim(strings.TrimPrefix(labels, "state="), `"`)
+		count, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		result[state] = int(count)
+	}
+	return result, scanner.Err()
+}
@@ -18,6 +18,8 @@ package tests
 
 import (
 	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -37,6 +39,17 @@ func TestCron(t *testing.T) {
 	id1 := ""
 	t.Run("create schedule user1 deployment-1", createSchedule(config, "* * * * *", "deployment-1", "user1", &id1, nil, nil, nil))
 	time.Sleep(61 * time.Second)
+	t.Run("read run info", readSchedule(config, "user1", id1, http.StatusOK, func(t *testing.T, info model.ScheduleEntryInfo) {
+		if info.NextRun == nil || !info.NextRun.After(time.Now()) {
+			t.Error("unexpected next run", info.NextRun)
+		}
+		if info.LastRun == nil || info.LastRun.Status != model.ExecutionStatusSuccess || info.LastRun.StatusCode != http.StatusOK {
+			t.Error("unexpected last run", info.LastRun)
+		}
+		if info.FailureCount != 0 {
+			t.Error("unexpected failure count", info.FailureCount)
+		}
+	}))
 	t.Run("delete id2", deleteSchedule(config, "user1", id1))
 
 	if len(processRequests) != 1 {
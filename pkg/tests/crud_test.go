@@ -29,6 +29,7 @@ import (
 	"net/url"
 	"reflect"
 	"sort"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -56,40 +57,47 @@ func TestCrud(t *testing.T) {
 	t.Run("create schedule user1 deployment-2", createSchedule(config, "* * * ? *", "deployment-2", "user1", &id2, nil, nil, nil))
 	t.Run("create schedule user2 deployment-3", createSchedule(config, "* * * ? *", "deployment-3", "user2", &id3, nil, nil, nil))
 
-	t.Run("read schedule user1 deployment-1", readSchedule(config, "* * * ? *", "deployment-1", "user1", id1, &alias1, &bTrue, nil))
-	t.Run("read schedule user1 deployment-2", readSchedule(config, "* * * ? *", "deployment-2", "user1", id2, nil, nil, nil))
-	t.Run("read schedule user2 deployment-3", readSchedule(config, "* * * ? *", "deployment-3", "user2", id3, nil, nil, nil))
+	t.Run("read schedule user1 deployment-1", readSchedule(config, "user1", id1, http.StatusOK, expectSchedule("* * * ? *", "deployment-1", &alias1, &bTrue, nil)))
+	t.Run("read schedule user1 deployment-2", readSchedule(config, "user1", id2, http.StatusOK, expectSchedule("* * * ? *", "deployment-2", nil, nil, nil)))
+	t.Run("read schedule user2 deployment-3", readSchedule(config, "user2", id3, http.StatusOK, expectSchedule("* * * ? *", "deployment-3", nil, nil, nil)))
 
 	t.Run("update schedule user1 deployment-1", updateSchedule(config, "* * * * ?", "deployment-1", "user1", id1, &alias2, &bFalse, nil))
 	t.Run("update schedule user1 deployment-4", updateSchedule(config, "* * * ? *", "deployment-4", "user1", id2, nil, nil, nil))
 
-	t.Run("read update schedule user1 deployment-1", readSchedule(config, "* * * * ?", "deployment-1", "user1", id1, &alias2, &bFalse, nil))
-	t.Run("read update schedule user1 deployment-4", readSchedule(config, "* * * ? *", "deployment-4", "user1", id2, nil, nil, nil))
+	t.Run("read update schedule user1 deployment-1", readSchedule(config, "user1", id1, http.StatusOK, expectSchedule("* * * * ?", "deployment-1", &alias2, &bFalse, nil)))
+	t.Run("read update schedule user1 deployment-4", readSchedule(config, "user1", id2, http.StatusOK, expectSchedule("* * * ? *", "deployment-4", nil, nil, nil)))
 
 	t.Run("list user1", listSchedules(config, "user1", []model.ScheduleEntry{{
 		Id:                  id1,
 		Cron:                "* * * * ?",
 		ProcessDeploymentId: "deployment-1",
+		Version:             2,
 		ProcessAlias:        &alias2,
 		Disabled:            &bFalse,
 	}, {
 		Id:                  id2,
 		Cron:                "* * * ? *",
 		ProcessDeploymentId: "deployment-4",
+		Version:             2,
 	}}, nil))
 
 	t.Run("list user2", listSchedules(config, "user2", []model.ScheduleEntry{{
 		Id:                  id3,
 		Cron:                "* * * ? *",
 		ProcessDeploymentId: "deployment-3",
+		Version:             1,
 	}}, nil))
 
+	t.Run("read schedule of other user", readSchedule(config, "user2", id1, http.StatusNotFound, nil))
+
 	t.Run("delete id2", deleteSchedule(config, "user1", id2))
+	t.Run("read deleted schedule", readSchedule(config, "user1", id2, http.StatusNotFound, nil))
 
 	t.Run("list user1", listSchedules(config, "user1", []model.ScheduleEntry{{
 		Id:                  id1,
 		Cron:                "* * * * ?",
 		ProcessDeploymentId: "deployment-1",
+		Version:             2,
 		ProcessAlias:        &alias2,
 		Disabled:            &bFalse,
 	}}, nil))
@@ -98,6 +106,7 @@ func TestCrud(t *testing.T) {
 		Id:                  id3,
 		Cron:                "* * * ? *",
 		ProcessDeploymentId: "deployment-3",
+		Version:             1,
 	}}, nil))
 
 	t1 := "t1"
@@ -108,6 +117,7 @@ func TestCrud(t *testing.T) {
 		Id:                  id1,
 		Cron:                "* * * ? *",
 		ProcessDeploymentId: "deployment-1",
+		Version:             1,
 		ProcessAlias:        nil,
 		Disabled:            nil,
 		CreatedBy:           &t1,
@@ -116,6 +126,7 @@ func TestCrud(t *testing.T) {
 		Id:                  id2,
 		Cron:                "* * * ? *",
 		ProcessDeploymentId: "deployment-1",
+		Version:             1,
 		ProcessAlias:        nil,
 		Disabled:            nil,
 		CreatedBy:           &t2,
@@ -226,20 +237,20 @@ func updateSchedule(config configuration.Config, cron string, deploymentId strin
 	}
 }
 
-func readSchedule(config configuration.Config, cron string, deploymentId string, userId string, entryId string, alias *string, disabled *bool, createdBy *string) func(t *testing.T) {
+// readSchedule reads the entry as userId and passes it to check if the request succeeded; check may be nil
+func readSchedule(config configuration.Config, userId string, entryId string, expectedCode int, check func(t *testing.T, info model.ScheduleEntryInfo)) func(t *testing.T) {
 	return func(t *testing.T) {
-		t.Skip("no read of single schedule implemented")
 		endpoint := "http://localhost:" + config.ApiPort
 		path := "/schedules/" + url.PathEscape(entryId)
 		method := "GET"
 		log.Println("HTTP-CALL=", method, endpoint+path)
-		req, err := http.NewRequest(method, endpoint+path, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, nil)
 		if err != nil {
 			t.Error(err)
 			return
 		}
-		ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-		req.WithContext(ctx)
 		err = processapi.SetAuthToken(req, userId)
 		if err != nil {
 			t.Error(err)
@@ -251,19 +262,31 @@ func readSchedule(config configuration.Config, cron string, deploymentId string,
 			return
 		}
 		defer resp.Body.Close()
-		if resp.StatusCode >= 300 {
+		if resp.StatusCode != expectedCode {
 			buf := new(bytes.Buffer)
 			buf.ReadFrom(resp.Body)
-			err = errors.New(resp.Status + ": " + buf.String())
-			t.Error(err)
+			t.Error(resp.StatusCode, expectedCode, buf.String())
 			return
 		}
-		result := model.ScheduleEntry{}
+		if resp.StatusCode >= 300 || check == nil {
+			return
+		}
+		result := model.ScheduleEntryInfo{}
 		err = json.NewDecoder(resp.Body).Decode(&result)
 		if err != nil {
 			t.Error(err)
 			return
 		}
+		if resp.Header.Get("ETag") != strconv.Quote(strconv.FormatInt(result.Version, 10)) {
+			t.Error(resp.Header.Get("ETag"), result.Version)
+		}
+		check(t, result)
+	}
+}
+
+// expectSchedule checks the user defined fields of a read entry
+func expectSchedule(cron string, deploymentId string, alias *string, disabled *bool, createdBy *string) func(t *testing.T, result model.ScheduleEntryInfo) {
+	return func(t *testing.T, result model.ScheduleEntryInfo) {
 		if result.ProcessDeploymentId != deploymentId {
 			t.Error(result.ProcessDeploymentId, deploymentId)
 			return
@@ -379,6 +402,11 @@ func listSchedules(config configuration.Config, userId string, expected []model.
 			t.Error(err)
 			return
 		}
+		for i := range result {
+			//computed by the server
+			result[i].CreatedAt = time.Time{}
+			result[i].NextRun = nil
+		}
 		sort.Slice(expected, func(i, j int) bool {
 			return expected[i].Id < expected[j].Id
 		})
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	alias := "alias"
+	id1 := ""
+	id2 := ""
+	t.Run("create 1", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "dev-deployment-1", ProcessAlias: &alias, Tags: []string{"a"}}, &id1))
+	time.Sleep(10 * time.Millisecond)
+	t.Run("create 2", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "dev-deployment-2"}, &id2))
+
+	document := model.ExportDocument{}
+	t.Run("export json", func(t *testing.T) {
+		body, err := exportRequest(config, "user1", "")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = json.Unmarshal(body, &document)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if document.Version != model.ExportDocumentVersion || len(document.Schedules) != 2 {
+			t.Error(string(body))
+			return
+		}
+		if document.Schedules[0].Id != id1 || document.Schedules[0].ProcessAlias == nil || *document.Schedules[0].ProcessAlias != alias || len(document.Schedules[0].Tags) != 1 {
+			t.Error(document.Schedules[0])
+		}
+		if document.Schedules[1].Id != id2 || document.Schedules[1].ProcessDeploymentId != "dev-deployment-2" {
+			t.Error(document.Schedules[1])
+		}
+	})
+
+	t.Run("export yaml", func(t *testing.T) {
+		body, err := exportRequest(config, "user1", "?format=yaml")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		generic := map[string]interface{}{}
+		err = yaml.Unmarshal(body, &generic)
+		if err != nil {
+			t.Error(err, string(body))
+			return
+		}
+		schedules, _ := generic["schedules"].([]interface{})
+		if len(schedules) != 2 {
+			t.Error(string(body))
+			return
+		}
+		first, _ := schedules[0].(map[string]interface{})
+		if first["process_deployment_id"] != "dev-deployment-1" {
+			t.Error(string(body))
+		}
+	})
+
+	t.Run("dry run skip", importRequest(config, "user1", "application/json", model.ImportRequest{DryRun: true, Document: document}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionSkip, model.ImportActionSkip)
+	}))
+
+	t.Run("dry run overwrite", importRequest(config, "user1", "application/json", model.ImportRequest{DryRun: true, Strategy: model.ImportStrategyOverwrite, Document: document}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionOverwrite, model.ImportActionOverwrite)
+	}))
+	t.Run("dry run did not change anything", listScheduleIds(config, "user1", "?sort=created_at&process_deployment_id=dev-deployment-1", 1, &[]string{id1}, 0))
+
+	t.Run("overwrite with deployment mapping", importRequest(config, "user1", "application/json", model.ImportRequest{
+		Strategy:          model.ImportStrategyOverwrite,
+		DeploymentMapping: map[string]string{"dev-deployment-1": "prod-deployment-1"},
+		Document:          document,
+	}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionOverwrite, model.ImportActionOverwrite)
+	}))
+	t.Run("check mapped deployment", listScheduleIds(config, "user1", "?process_deployment_id=prod-deployment-1", 1, &[]string{id1}, 0))
+
+	t.Run("duplicate", importRequest(config, "user1", "application/json", model.ImportRequest{Strategy: model.ImportStrategyDuplicate, Document: document}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionDuplicate, model.ImportActionDuplicate)
+		for _, result := range response.Results {
+			if result.TargetId == result.SourceId || result.TargetId == "" {
+				t.Error(result)
+			}
+		}
+	}))
+	t.Run("check duplicates", expectScheduleCount(config, "user1", "?process_deployment_id=dev-deployment-2", 2))
+
+	t.Run("other user dry run without id mapping", importRequest(config, "user2", "application/json", model.ImportRequest{DryRun: true, Document: document}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionConflict, model.ImportActionConflict)
+	}))
+	t.Run("other user without id mapping", importRequest(config, "user2", "application/json", model.ImportRequest{Document: document}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionConflict, model.ImportActionConflict)
+	}))
+
+	t.Run("other user with id mapping", importRequest(config, "user2", "application/json", model.ImportRequest{
+		IdMapping: map[string]string{id1: "user2-id1", id2: "user2-id2"},
+		Document:  document,
+	}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionCreate, model.ImportActionCreate)
+	}))
+	t.Run("check user2", listScheduleIds(config, "user2", "?sort=id", 2, &[]string{"user2-id1", "user2-id2"}, 0, 1))
+
+	t.Run("reserved id", importRequest(config, "user4", "application/json", model.ImportRequest{
+		IdMapping: map[string]string{id1: "export", id2: "user4-id2"},
+		Document:  document,
+	}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionError, model.ImportActionCreate)
+		if len(response.Results) > 0 && !strings.Contains(response.Results[0].Error, model.ErrorReservedId.Error()) {
+			t.Error(response.Results[0].Error)
+		}
+	}))
+
+	t.Run("invalid id", importRequest(config, "user4", "application/json", model.ImportRequest{
+		IdMapping: map[string]string{id1: "../user4-id1", id2: strings.Repeat("x", 65)},
+		Document:  document,
+	}, func(t *testing.T, response model.ImportResponse) {
+		expectImportActions(t, response, model.ImportActionError, model.ImportActionError)
+		for _, result := range response.Results {
+			if result.Error != model.ErrorInvalidId.Error() {
+				t.Error(result.Error)
+			}
+		}
+	}))
+
+	t.Run("yaml import", func(t *testing.T) {
+		temp, _ := json.Marshal(model.ImportRequest{
+			IdMapping: map[string]string{id1: "user3-id1", id2: "user3-id2"},
+			Document:  document,
+		})
+		var generic interface{}
+		_ = json.Unmarshal(temp, &generic)
+		body, err := yaml.Marshal(generic)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		importRawRequest(config, "user3", "application/yaml", "", body, func(t *testing.T, response model.ImportResponse) {
+			expectImportActions(t, response, model.ImportActionCreate, model.ImportActionCreate)
+		})(t)
+	})
+
+	t.Run("plain document with query options", func(t *testing.T) {
+		body, _ := json.Marshal(document)
+		importRawRequest(config, "user1", "application/json", "?strategy=skip&dry_run=true", body, func(t *testing.T, response model.ImportResponse) {
+			if !response.DryRun {
+				t.Error("expected dry run")
+			}
+			expectImportActions(t, response, model.ImportActionSkip, model.ImportActionSkip)
+		})(t)
+	})
+}
+
+func expectImportActions(t *testing.T, response model.ImportResponse, actions ...string) {
+	if len(response.Results) != len(actions) {
+		t.Error(response.Results, actions)
+		return
+	}
+	for i, result := range response.Results {
+		if result.Action != actions[i] {
+			t.Error(i, result, actions[i])
+		}
+	}
+}
+
+func expectScheduleCount(config configuration.Config, userId string, query string, expected int) func(t *testing.T) {
+	return func(t *testing.T) {
+		resp, err := listSchedulesRequest(config, userId, query)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		result := []model.ScheduleEntry{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(result) != expected {
+			t.Error(len(result), expected)
+		}
+	}
+}
+
+func exportRequest(config configuration.Config, userId string, query string) ([]byte, error) {
+	endpoint := "http://localhost:" + config.ApiPort
+	path := "/schedules/export" + query
+	method := "GET"
+	log.Println("HTTP-CALL=", method, endpoint+path)
+	req, err := http.NewRequest(method, endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = processapi.SetAuthToken(req, userId)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func importRequest(config configuration.Config, userId string, contentType string, request model.ImportRequest, check func(t *testing.T, response model.ImportResponse)) func(t *testing.T) {
+	body, _ := json.Marshal(request)
+	return importRawRequest(config, userId, contentType, "", body, check)
+}
+
+func importRawRequest(config configuration.Config, userId string, contentType string, query string, body []byte, check func(t *testing.T, response model.ImportResponse)) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		path := "/schedules/import" + query
+		method := "POST"
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		req, err := http.NewRequest(method, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Set("Content-Type", contentType)
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			temp, _ := io.ReadAll(resp.Body)
+			t.Error(resp.StatusCode, string(temp))
+			return
+		}
+		response := model.ImportResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		check(t, response)
+	}
+}
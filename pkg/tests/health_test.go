@@ -0,0 +1,73 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	t.Run("live", checkHealth(config, "/health/live", http.StatusOK, func(t *testing.T, report model.HealthReport) {
+		if report.Status != model.HealthStatusUp {
+			t.Error(report)
+		}
+	}))
+
+	t.Run("ready", checkHealth(config, "/health/ready", http.StatusOK, func(t *testing.T, report model.HealthReport) {
+		if report.Status != model.HealthStatusUp {
+			t.Error(report)
+		}
+		for _, component := range []string{model.HealthComponentMongo, model.HealthComponentScheduler, model.HealthComponentProcessEndpoint} {
+			if report.Components[component].Status != model.HealthStatusUp {
+				t.Error(component, report.Components[component])
+			}
+		}
+		if report.Components[model.HealthComponentLeader].Status != model.HealthStatusNotApplicable {
+			t.Error(report.Components[model.HealthComponentLeader])
+		}
+	}))
+}
+
+func checkHealth(config configuration.Config, path string, expectedCode int, check func(t *testing.T, report model.HealthReport)) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Get("http://localhost:" + config.ApiPort + path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			t.Error(resp.StatusCode, expectedCode)
+		}
+		report := model.HealthReport{}
+		err = json.NewDecoder(resp.Body).Decode(&report)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		check(t, report)
+	}
+}
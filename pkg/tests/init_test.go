@@ -19,7 +19,6 @@ package tests
 import (
 	"context"
 	"github.com/SENERGY-Platform/process-scheduler/pkg"
-	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/tests/services"
 	"sync"
@@ -44,12 +43,7 @@ func TestInit(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	config := &configuration.ConfigStruct{
-		ApiPort:         apiPort,
-		MongoUrl:        "mongodb://" + ip + ":27017",
-		MongoTable:      "test",
-		MongoCollection: "test",
-	}
+	config := testConfig(apiPort, ip)
 	var processApiRequests chan string
 	config.ProcessEndpoint, processApiRequests = services.ProcessApiServer(ctx1, wg1)
 	wg2, err := pkg.Start(ctx2, config)
@@ -79,6 +73,7 @@ func TestInit(t *testing.T) {
 		Id:                  id1,
 		Cron:                "* * * * *",
 		ProcessDeploymentId: "deployment-1",
+		Version:             1,
 	}}, nil))
 
 	time.Sleep(time.Minute)
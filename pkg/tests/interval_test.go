@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIntervalSchedule(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	anchor := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	months, _ := json.Marshal(model.ScheduleEntry{Interval: &model.IntervalTrigger{Every: "P1M", Anchor: anchor}, ProcessDeploymentId: "d"})
+	t.Run("calendar duration", authRequest(config, "user1", "POST", "/schedules", "application/json", months, http.StatusBadRequest, nil))
+	missingAnchor, _ := json.Marshal(model.ScheduleEntry{Interval: &model.IntervalTrigger{Every: "PT90M"}, ProcessDeploymentId: "d"})
+	t.Run("missing anchor", authRequest(config, "user1", "POST", "/schedules", "application/json", missingAnchor, http.StatusBadRequest, nil))
+
+	id := ""
+	t.Run("create", createScheduleEntry(config, "user1", model.ScheduleEntry{Interval: &model.IntervalTrigger{Every: "PT90M", Anchor: anchor}, ProcessDeploymentId: "d"}, &id))
+	t.Run("next runs", func(t *testing.T) {
+		runs := []model.PlannedRun{}
+		authRequest(config, "user1", "GET", "/schedules/"+id+"/next?count=3", "", nil, http.StatusOK, &runs)(t)
+		if len(runs) != 3 {
+			t.Error(runs)
+			return
+		}
+		for i, run := range runs {
+			if run.Time.Sub(anchor)%(90*time.Minute) != 0 || !run.Time.After(time.Now()) {
+				t.Error(run.Time)
+			}
+			if i > 0 && run.Time.Sub(runs[i-1].Time) != 90*time.Minute {
+				t.Error(runs)
+			}
+		}
+	})
+}
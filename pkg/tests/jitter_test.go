@@ -0,0 +1,56 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+	config, processRequests := startTestService(t, nil)
+
+	invalid, _ := json.Marshal(model.ScheduleEntry{Cron: "0 * * * *", Jitter: "2h", ProcessDeploymentId: "d"})
+	t.Run("jitter above max", authRequest(config, "user1", "POST", "/schedules", "application/json", invalid, http.StatusBadRequest, nil))
+
+	id := ""
+	t.Run("create", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "*/2 * * * * *", Jitter: "1500ms", ProcessDeploymentId: "deployment-1"}, &id))
+	time.Sleep(5 * time.Second)
+	t.Run("read run info", readSchedule(config, "user1", id, http.StatusOK, func(t *testing.T, info model.ScheduleEntryInfo) {
+		if info.Jitter != "1500ms" {
+			t.Error("unexpected jitter", info.Jitter)
+		}
+		if info.LastRun == nil || info.LastRun.Status != model.ExecutionStatusSuccess {
+			t.Error("unexpected last run", info.LastRun)
+			return
+		}
+		if info.LastRun.Delay != "" {
+			delay, err := time.ParseDuration(info.LastRun.Delay)
+			if err != nil || delay > 1600*time.Millisecond {
+				t.Error("unexpected delay", info.LastRun.Delay, err)
+			}
+		}
+	}))
+	t.Run("delete", deleteSchedule(config, "user1", id))
+	if len(processRequests) == 0 {
+		t.Error("expected process starts")
+	}
+}
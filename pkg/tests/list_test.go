@@ -0,0 +1,189 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestListOptions(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	aliasA := "Alpha Process"
+	aliasB := "beta process"
+	aliasC := "gamma"
+	bTrue := true
+	ids := make([]string, 4)
+	t.Run("create 0", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "0 0 29 2 *", ProcessDeploymentId: "deployment-1", ProcessAlias: &aliasC, Tags: []string{"a", "b"}}, &ids[0]))
+	time.Sleep(10 * time.Millisecond)
+	t.Run("create 1", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1", ProcessAlias: &aliasA, Tags: []string{"a"}}, &ids[1]))
+	time.Sleep(10 * time.Millisecond)
+	t.Run("create 2", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "deployment-2", ProcessAlias: &aliasB, Disabled: &bTrue}, &ids[2]))
+	time.Sleep(10 * time.Millisecond)
+	t.Run("create 3", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "0 0 * * *", ProcessDeploymentId: "deployment-2", Tags: []string{"b"}}, &ids[3]))
+	t.Run("create other user", createScheduleEntry(config, "user2", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1"}, nil))
+
+	t.Run("all by created_at", listScheduleIds(config, "user1", "?sort=created_at", 4, &ids, 0, 1, 2, 3))
+	t.Run("all by created_at desc", listScheduleIds(config, "user1", "?sort=created_at.desc", 4, &ids, 3, 2, 1, 0))
+	t.Run("page 1", listScheduleIds(config, "user1", "?sort=created_at&limit=2", 4, &ids, 0, 1))
+	t.Run("page 2", listScheduleIds(config, "user1", "?sort=created_at&limit=2&offset=2", 4, &ids, 2, 3))
+	t.Run("page 3", listScheduleIds(config, "user1", "?sort=created_at&limit=2&offset=4", 4, &ids))
+	t.Run("by alias", listScheduleIds(config, "user1", "?sort=alias", 4, &ids, 3, 1, 2, 0))
+	t.Run("by next run", listScheduleIds(config, "user1", "?sort=next_run&disabled=false", 3, &ids, 1, 3, 0))
+	t.Run("deployment", listScheduleIds(config, "user1", "?sort=created_at&process_deployment_id=deployment-2", 2, &ids, 2, 3))
+	t.Run("disabled", listScheduleIds(config, "user1", "?disabled=true", 1, &ids, 2))
+	t.Run("enabled", listScheduleIds(config, "user1", "?sort=created_at&disabled=false", 3, &ids, 0, 1, 3))
+	t.Run("search", listScheduleIds(config, "user1", "?sort=created_at&search=PROCESS", 2, &ids, 1, 2))
+	t.Run("tag a", listScheduleIds(config, "user1", "?sort=created_at&tags=a", 2, &ids, 0, 1))
+	t.Run("tags a,b", listScheduleIds(config, "user1", "?tags=a,b", 1, &ids, 0))
+	t.Run("combined", listScheduleIds(config, "user1", "?sort=created_at&tags=b&process_deployment_id=deployment-1", 1, &ids, 0))
+	t.Run("invalid sort", listScheduleIdsStatus(config, "user1", "?sort=foo", http.StatusBadRequest))
+	t.Run("invalid limit", listScheduleIdsStatus(config, "user1", "?limit=-1", http.StatusBadRequest))
+	t.Run("unlimited limit", listScheduleIdsStatus(config, "user1", "?limit=0", http.StatusBadRequest))
+	t.Run("limit above max", listScheduleIdsStatus(config, "user1", "?limit=1001", http.StatusBadRequest))
+	t.Run("invalid disabled", listScheduleIdsStatus(config, "user1", "?disabled=foo", http.StatusBadRequest))
+}
+
+func createScheduleEntry(config configuration.Config, userId string, entry model.ScheduleEntry, entryId *string) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		path := "/schedules"
+		method := "POST"
+		buf := bytes.NewBuffer([]byte{})
+		err := json.NewEncoder(buf).Encode(entry)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			t.Error(errors.New(resp.Status + ": " + buf.String()))
+			return
+		}
+		result := model.ScheduleEntry{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if entryId != nil {
+			*entryId = result.Id
+		}
+	}
+}
+
+func listScheduleIdsStatus(config configuration.Config, userId string, query string, expectedCode int) func(t *testing.T) {
+	return func(t *testing.T) {
+		resp, err := listSchedulesRequest(config, userId, query)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			t.Error(resp.StatusCode, expectedCode)
+		}
+	}
+}
+
+// listScheduleIds expects the ids[indexes...] in the given order
+func listScheduleIds(config configuration.Config, userId string, query string, expectedTotal int, ids *[]string, indexes ...int) func(t *testing.T) {
+	return func(t *testing.T) {
+		resp, err := listSchedulesRequest(config, userId, query)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			t.Error(errors.New(resp.Status + ": " + buf.String()))
+			return
+		}
+		if total := resp.Header.Get("X-Total-Count"); total != strconv.Itoa(expectedTotal) {
+			t.Error("unexpected total", total, expectedTotal)
+		}
+		result := []model.ScheduleEntry{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		actual := []string{}
+		for _, entry := range result {
+			actual = append(actual, entry.Id)
+		}
+		expected := []string{}
+		for _, index := range indexes {
+			expected = append(expected, (*ids)[index])
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Error(actual, expected)
+		}
+	}
+}
+
+func listSchedulesRequest(config configuration.Config, userId string, query string) (*http.Response, error) {
+	endpoint := "http://localhost:" + config.ApiPort
+	path := "/schedules" + query
+	method := "GET"
+	log.Println("HTTP-CALL=", method, endpoint+path)
+	req, err := http.NewRequest(method, endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = processapi.SetAuthToken(req, userId)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	return client.Do(req)
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/persistence"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tests/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+	_, ip, err := services.MongoContainer(ctx, wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	config := testConfig("", ip)
+
+	db, err := persistence.New(ctx, wg, config, nil, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	version, err := db.(*persistence.Persistence).SchemaVersion()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if version < 1 {
+		t.Error(version)
+		return
+	}
+
+	t.Run("restart is idempotent", func(t *testing.T) {
+		db2, err := persistence.New(ctx, wg, config, nil, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		version2, err := db2.(*persistence.Persistence).SchemaVersion()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if version2 != version {
+			t.Error(version2, version)
+		}
+	})
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.MongoUrl))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer client.Disconnect(context.Background())
+
+	t.Run("indexes", func(t *testing.T) {
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		cursor, err := client.Database(config.MongoTable).Collection(config.MongoCollection).Indexes().List(timeout)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		indexes := []bson.M{}
+		err = cursor.All(timeout, &indexes)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		found := map[string]bson.M{}
+		for _, index := range indexes {
+			found[index["name"].(string)] = index
+		}
+		for _, name := range []string{"id_unique", "user_id", "user_created_by"} {
+			if _, ok := found[name]; !ok {
+				t.Error("missing index", name, indexes)
+			}
+		}
+		if unique, _ := found["id_unique"]["unique"].(bool); !unique {
+			t.Error("id index is not unique", found["id_unique"])
+		}
+	})
+
+	t.Run("unique id", func(t *testing.T) {
+		err = db.Set(context.Background(), model.ScheduleEntry{Id: "duplicate", User: "user1", Cron: "* * * * *", ProcessDeploymentId: "deployment-1"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = db.Set(context.Background(), model.ScheduleEntry{Id: "duplicate", User: "user2", Cron: "* * * * *", ProcessDeploymentId: "deployment-1"})
+		if err != model.ErrorDuplicateId {
+			t.Error("expected duplicate id error", err)
+		}
+	})
+}
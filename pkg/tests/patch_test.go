@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"log"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	id := ""
+	alias := "alias"
+	createdBy := "creator"
+	bTrue := true
+	t.Run("create", createSchedule(config, "* * * ? *", "deployment-1", "user1", &id, &alias, nil, &createdBy))
+
+	t.Run("disable", patchSchedule(config, "user1", id, `{"disabled": true}`, http.StatusOK))
+	t.Run("check disabled", listSchedules(config, "user1", []model.ScheduleEntry{{
+		Id:                  id,
+		Cron:                "* * * ? *",
+		ProcessDeploymentId: "deployment-1",
+		ProcessAlias:        &alias,
+		Disabled:            &bTrue,
+		CreatedBy:           &createdBy,
+		Version:             2,
+	}}, nil))
+
+	t.Run("remove alias", patchSchedule(config, "user1", id, `{"process_alias": null, "cron": "*/5 * * * *"}`, http.StatusOK))
+	t.Run("check alias removed", listSchedules(config, "user1", []model.ScheduleEntry{{
+		Id:                  id,
+		Cron:                "*/5 * * * *",
+		ProcessDeploymentId: "deployment-1",
+		Disabled:            &bTrue,
+		CreatedBy:           &createdBy,
+		Version:             3,
+	}}, nil))
+
+	t.Run("invalid cron", patchSchedule(config, "user1", id, `{"cron": "foo"}`, http.StatusBadRequest))
+	t.Run("missing deployment", patchSchedule(config, "user1", id, `{"process_deployment_id": null}`, http.StatusBadRequest))
+	t.Run("change id", patchSchedule(config, "user1", id, `{"id": "foo"}`, http.StatusBadRequest))
+	t.Run("invalid json", patchSchedule(config, "user1", id, `{"cron": `, http.StatusBadRequest))
+	t.Run("other user", patchSchedule(config, "user2", id, `{"disabled": false}`, http.StatusNotFound))
+
+	t.Run("check unchanged", listSchedules(config, "user1", []model.ScheduleEntry{{
+		Id:                  id,
+		Cron:                "*/5 * * * *",
+		ProcessDeploymentId: "deployment-1",
+		Disabled:            &bTrue,
+		CreatedBy:           &createdBy,
+		Version:             3,
+	}}, nil))
+
+	t.Run("delete", deleteSchedule(config, "user1", id))
+}
+
+func patchSchedule(config configuration.Config, userId string, entryId string, patch string, expectedCode int) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		path := "/schedules/" + url.PathEscape(entryId)
+		method := "PATCH"
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewBufferString(patch))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			t.Error(resp.StatusCode, expectedCode, buf.String())
+			return
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"log"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	bTrue := true
+	ids := []string{"", "", "", "", ""}
+	t.Run("create 0", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1"}, &ids[0]))
+	t.Run("create 1", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1", Disabled: &bTrue}, &ids[1]))
+	t.Run("create 2", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-2", Tags: []string{"maintenance"}}, &ids[2]))
+	t.Run("create 3", createScheduleEntry(config, "user2", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1"}, &ids[3]))
+
+	t.Run("missing selector", pauseRequest(config, "user1", "/schedules/pause", model.PauseSelector{}, http.StatusBadRequest, nil))
+
+	t.Run("pause deployment", pauseRequest(config, "user1", "/schedules/pause", model.PauseSelector{ProcessDeploymentId: "deployment-1"}, http.StatusOK, []string{ids[0], ids[1]}))
+	t.Run("pause again", pauseRequest(config, "user1", "/schedules/pause", model.PauseSelector{ProcessDeploymentId: "deployment-1"}, http.StatusOK, []string{}))
+	t.Run("list paused", listScheduleIds(config, "user1", "?paused=true&sort=created_at", 2, &ids, 0, 1))
+	t.Run("other user not paused", listScheduleIds(config, "user2", "?paused=true", 0, &ids))
+	t.Run("paused entry has no next run", readSchedule(config, "user1", ids[0], http.StatusOK, func(t *testing.T, info model.ScheduleEntryInfo) {
+		if !info.Paused || info.NextRun != nil {
+			t.Error(info.Paused, info.NextRun)
+		}
+	}))
+
+	t.Run("pause tag", pauseRequest(config, "user1", "/schedules/pause", model.PauseSelector{Tags: []string{"maintenance"}}, http.StatusOK, []string{ids[2]}))
+	t.Run("create 4 for paused deployment", createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1", Tags: []string{"maintenance"}}, &ids[4]))
+	t.Run("new entry paused", listScheduleIds(config, "user1", "?paused=true&sort=created_at", 4, &ids, 0, 1, 2, 4))
+	t.Run("resume all without all pause", pauseRequest(config, "user1", "/schedules/resume", model.PauseSelector{All: true}, http.StatusOK, []string{}))
+	t.Run("resume tag", pauseRequest(config, "user1", "/schedules/resume", model.PauseSelector{Tags: []string{"maintenance"}}, http.StatusOK, []string{ids[2]}))
+	t.Run("resume deployment", pauseRequest(config, "user1", "/schedules/resume", model.PauseSelector{ProcessDeploymentId: "deployment-1"}, http.StatusOK, []string{ids[0], ids[1], ids[4]}))
+	t.Run("list not paused", listScheduleIds(config, "user1", "?paused=false&sort=created_at", 4, &ids, 0, 1, 2, 4))
+	t.Run("disabled state restored", listScheduleIds(config, "user1", "?disabled=true", 1, &ids, 1))
+	t.Run("resumed entry has next run", readSchedule(config, "user1", ids[0], http.StatusOK, func(t *testing.T, info model.ScheduleEntryInfo) {
+		if info.Paused || info.NextRun == nil {
+			t.Error(info.Paused, info.NextRun)
+		}
+	}))
+}
+
+// pauseRequest sends the selector to path (/schedules/pause or /schedules/resume) and compares the changed ids with expectedIds (ignoring order)
+func pauseRequest(config configuration.Config, userId string, path string, selector model.PauseSelector, expectedCode int, expectedIds []string) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		method := "POST"
+		body, err := json.Marshal(selector)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			t.Error(resp.StatusCode, expectedCode, buf.String())
+			return
+		}
+		if expectedCode != http.StatusOK {
+			return
+		}
+		result := model.PauseResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(result.Failed) > 0 {
+			t.Error(result.Failed)
+		}
+		sort.Strings(result.Ids)
+		expected := append([]string{}, expectedIds...)
+		sort.Strings(expected)
+		if len(result.Ids) != len(expected) {
+			t.Error(result.Ids, expected)
+			return
+		}
+		for i := range expected {
+			if result.Ids[i] != expected[i] {
+				t.Error(result.Ids, expected)
+				return
+			}
+		}
+	}
+}
@@ -17,16 +17,29 @@
 package tests
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"github.com/SENERGY-Platform/process-scheduler/pkg"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
 	"github.com/SENERGY-Platform/process-scheduler/pkg/tests/services"
+	"io"
+	"log"
 	"net"
+	"net/http"
 	"strconv"
 	"sync"
+	"testing"
+	"time"
 )
 
 func Start(ctx context.Context) (wg *sync.WaitGroup, config configuration.Config, processApiRequests chan string, err error) {
+	return StartWithConfig(ctx, nil)
+}
+
+// StartWithConfig is Start with a callback to modify the test configuration; configure may be nil
+func StartWithConfig(ctx context.Context, configure func(config configuration.Config)) (wg *sync.WaitGroup, config configuration.Config, processApiRequests chan string, err error) {
 	wg = &sync.WaitGroup{}
 	apiPort, err := getFreePort()
 	if err != nil {
@@ -36,13 +49,11 @@ func Start(ctx context.Context) (wg *sync.WaitGroup, config configuration.Config
 	if err != nil {
 		return wg, nil, nil, err
 	}
-	config = &configuration.ConfigStruct{
-		ApiPort:         apiPort,
-		MongoUrl:        "mongodb://" + ip + ":27017",
-		MongoTable:      "test",
-		MongoCollection: "test",
-	}
+	config = testConfig(apiPort, ip)
 	config.ProcessEndpoint, processApiRequests = services.ProcessApiServer(ctx, wg)
+	if configure != nil {
+		configure(config)
+	}
 	wg2, err := pkg.Start(ctx, config)
 	if err != nil {
 		return wg, config, processApiRequests, err
@@ -55,6 +66,79 @@ func Start(ctx context.Context) (wg *sync.WaitGroup, config configuration.Config
 	return
 }
 
+// testConfig returns the configuration of a service that uses the test collections of the mongodb at ip
+func testConfig(apiPort string, ip string) configuration.Config {
+	return &configuration.ConfigStruct{
+		ApiPort:                  apiPort,
+		MongoUrl:                 "mongodb://" + ip + ":27017",
+		MongoTable:               "test",
+		MongoCollection:          "test",
+		MongoMetadataCollection:  "test_metadata",
+		MongoExecutionCollection: "test_executions",
+		MongoBlackoutCollection:  "test_blackouts",
+		MongoCalendarCollection:  "test_calendars",
+		MongoQuotaCollection:     "test_quotas",
+		MongoQuotaLockCollection: "test_quota_locks",
+		MongoPauseCollection:     "test_pauses",
+	}
+}
+
+// startTestService is StartWithConfig for a single test; the service and its dependencies are stopped
+// after the test and its subtests are finished
+func startTestService(t *testing.T, configure func(config configuration.Config)) (config configuration.Config, processApiRequests chan string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	wg, config, processApiRequests, err := StartWithConfig(ctx, configure)
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config, processApiRequests
+}
+
+// authRequest sends the body to the api of the test service as userId and decodes the response into result (if not nil)
+func authRequest(config configuration.Config, userId string, method string, path string, contentType string, body []byte, expectedCode int, result interface{}) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			temp, _ := io.ReadAll(resp.Body)
+			t.Error(resp.StatusCode, expectedCode, string(temp))
+			return
+		}
+		if result != nil {
+			err = json.NewDecoder(resp.Body).Decode(result)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
 func getFreePort() (string, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
 	if err != nil {
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, func(config configuration.Config) {
+		config.WorkerPoolSize = 1
+		config.MaxPriority = map[string]string{model.DefaultRole: model.PriorityNormal, "admin": model.PriorityHigh}
+	})
+
+	high := model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "deployment-1", Priority: model.PriorityHigh}
+	t.Run("user high priority", blackoutRequest(config, false, "POST", "/schedules", high, http.StatusForbidden, nil))
+	t.Run("unknown priority", blackoutRequest(config, true, "POST", "/schedules", model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "deployment-1", Priority: "urgent"}, http.StatusBadRequest, nil))
+	t.Run("user low priority", blackoutRequest(config, false, "POST", "/schedules", model.ScheduleEntry{Cron: "0 0 1 1 *", ProcessDeploymentId: "deployment-1", Priority: model.PriorityLow}, http.StatusOK, nil))
+
+	created := model.ScheduleEntry{}
+	t.Run("admin high priority", blackoutRequest(config, true, "POST", "/schedules", high, http.StatusOK, &created))
+	t.Run("read", func(t *testing.T) {
+		info := model.ScheduleEntryInfo{}
+		blackoutRequest(config, true, "GET", "/schedules/"+created.Id, nil, http.StatusOK, &info)(t)
+		if info.Priority != model.PriorityHigh {
+			t.Error(info.Priority)
+		}
+	})
+	t.Run("delete", blackoutRequest(config, true, "DELETE", "/schedules/"+created.Id, nil, http.StatusOK, nil))
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, func(config configuration.Config) {
+		config.MaxSchedulesPerUser = 2
+	})
+
+	entry := model.ScheduleEntry{Cron: "0 * * * *", ProcessDeploymentId: "deployment-1"}
+	t.Run("create 1", blackoutRequest(config, false, "POST", "/schedules", entry, http.StatusOK, nil))
+	t.Run("create 2", blackoutRequest(config, false, "POST", "/schedules", entry, http.StatusOK, nil))
+	t.Run("create above quota", blackoutRequest(config, false, "POST", "/schedules", entry, http.StatusTooManyRequests, nil))
+
+	unlimited := int64(model.QuotaUnlimited)
+	t.Run("user can not override", blackoutRequest(config, false, "PUT", "/admin/quotas/user1", model.QuotaOverride{MaxSchedules: &unlimited}, http.StatusForbidden, nil))
+	t.Run("admin override", blackoutRequest(config, true, "PUT", "/admin/quotas/user1", model.QuotaOverride{MaxSchedules: &unlimited}, http.StatusOK, nil))
+	t.Run("create with override", blackoutRequest(config, false, "POST", "/schedules", entry, http.StatusOK, nil))
+	t.Run("read quota", func(t *testing.T) {
+		status := model.QuotaStatus{}
+		blackoutRequest(config, false, "GET", "/quota", nil, http.StatusOK, &status)(t)
+		if status.Schedules != 3 || status.Quota.MaxSchedules != nil || status.Override == nil || status.Override.UpdatedBy != "admin" {
+			t.Error(status)
+		}
+	})
+	t.Run("list overrides", func(t *testing.T) {
+		overrides := []model.QuotaOverride{}
+		blackoutRequest(config, true, "GET", "/admin/quotas", nil, http.StatusOK, &overrides)(t)
+		if len(overrides) != 1 || overrides[0].User != "user1" {
+			t.Error(overrides)
+		}
+	})
+	t.Run("remove override", blackoutRequest(config, true, "DELETE", "/admin/quotas/user1", nil, http.StatusOK, nil))
+	t.Run("create after removal", blackoutRequest(config, false, "POST", "/schedules", entry, http.StatusTooManyRequests, nil))
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+	config, processRequests := startTestService(t, func(config configuration.Config) {
+		config.UserExecutionRateLimit = 1
+		config.ExecutionOverflowPolicy = "drop"
+		config.MinExecutionInterval = map[string]string{model.DefaultRole: "1m", "admin": "1s"}
+	})
+
+	everySecond := model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "deployment-1"}
+	t.Run("user below min interval", blackoutRequest(config, false, "POST", "/schedules", everySecond, http.StatusBadRequest, nil))
+	t.Run("user at min interval", blackoutRequest(config, false, "POST", "/schedules", model.ScheduleEntry{Cron: "* * * * *", ProcessDeploymentId: "deployment-1"}, http.StatusOK, nil))
+
+	first, second := model.ScheduleEntry{}, model.ScheduleEntry{}
+	t.Run("admin first", blackoutRequest(config, true, "POST", "/schedules", everySecond, http.StatusOK, &first))
+	t.Run("admin second", blackoutRequest(config, true, "POST", "/schedules", everySecond, http.StatusOK, &second))
+	time.Sleep(3500 * time.Millisecond)
+	t.Run("throttled run is recorded", func(t *testing.T) {
+		throttled := 0
+		for _, id := range []string{first.Id, second.Id} {
+			info := model.ScheduleEntryInfo{}
+			blackoutRequest(config, true, "GET", "/schedules/"+id, nil, http.StatusOK, &info)(t)
+			if info.LastRun != nil && info.LastRun.Status == model.ExecutionStatusThrottled {
+				throttled++
+			}
+		}
+		if throttled == 0 {
+			t.Error("expected a throttled last run")
+		}
+	})
+	t.Run("delete first", blackoutRequest(config, true, "DELETE", "/schedules/"+first.Id, nil, http.StatusOK, nil))
+	t.Run("delete second", blackoutRequest(config, true, "DELETE", "/schedules/"+second.Id, nil, http.StatusOK, nil))
+
+	//two firings per second, but only one process start per second and user
+	if count := len(processRequests); count < 2 || count > 5 {
+		t.Error("unexpected process starts", count)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRruleSchedule(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	rrule := "DTSTART;TZID=Europe/Berlin:20240109T090000\nRRULE:FREQ=MONTHLY;BYDAY=2TU"
+	ambiguous, _ := json.Marshal(model.ScheduleEntry{Cron: "* * * * *", Rrule: rrule, ProcessDeploymentId: "d"})
+	t.Run("cron and rrule", authRequest(config, "user1", "POST", "/schedules", "application/json", ambiguous, http.StatusBadRequest, nil))
+	missingStart, _ := json.Marshal(model.ScheduleEntry{Rrule: "RRULE:FREQ=MONTHLY;BYDAY=2TU", ProcessDeploymentId: "d"})
+	t.Run("missing dtstart", authRequest(config, "user1", "POST", "/schedules", "application/json", missingStart, http.StatusBadRequest, nil))
+
+	id := ""
+	t.Run("create", createScheduleEntry(config, "user1", model.ScheduleEntry{Rrule: rrule, ProcessDeploymentId: "d"}, &id))
+	t.Run("next runs", func(t *testing.T) {
+		runs := []model.PlannedRun{}
+		authRequest(config, "user1", "GET", "/schedules/"+id+"/next?count=3", "", nil, http.StatusOK, &runs)(t)
+		if len(runs) != 3 {
+			t.Error(runs)
+			return
+		}
+		berlin, _ := time.LoadLocation("Europe/Berlin")
+		for _, run := range runs {
+			local := run.Time.In(berlin)
+			if local.Weekday() != time.Tuesday || local.Day() < 8 || local.Day() > 14 || local.Hour() != 9 || !run.Time.After(time.Now()) {
+				t.Error(local)
+			}
+		}
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/process-scheduler/pkg"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tests/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	t.Parallel()
+	t.Run("running executions finish within grace period", testShutdown("10s", 2*time.Second, model.ExecutionStatusSuccess))
+	t.Run("running executions are canceled after grace period", testShutdown("1s", 4*time.Second, model.ExecutionStatusFailed))
+}
+
+func testShutdown(gracePeriod string, processDuration time.Duration, expectedStatus string) func(t *testing.T) {
+	return func(t *testing.T) {
+		mongoCtx, stopMongo := context.WithCancel(context.Background())
+		mongoWg := &sync.WaitGroup{}
+		defer mongoWg.Wait()
+		defer stopMongo()
+		_, ip, err := services.MongoContainer(mongoCtx, mongoWg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		apiPort, err := getFreePort()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		startedMux := sync.Mutex{}
+		started := 0
+		firstStart := make(chan bool, 1)
+		processApi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startedMux.Lock()
+			started++
+			startedMux.Unlock()
+			select {
+			case firstStart <- true:
+			default:
+			}
+			select {
+			case <-time.After(processDuration):
+				w.WriteHeader(http.StatusOK)
+			case <-r.Context().Done():
+			}
+		}))
+		defer processApi.Close()
+
+		config := testConfig(apiPort, ip)
+		config.ProcessEndpoint = processApi.URL
+		config.ShutdownGracePeriod = gracePeriod
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wg, err := pkg.Start(ctx, config)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		id := ""
+		createScheduleEntry(config, "user1", model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "deployment-1"}, &id)(t)
+		select {
+		case <-firstStart:
+		case <-time.After(5 * time.Second):
+			t.Error("timeout")
+			return
+		}
+		cancel()
+		wg.Wait()
+
+		client, err := mongo.Connect(mongoCtx, options.Client().ApplyURI(config.MongoUrl))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer client.Disconnect(context.Background())
+		cursor, err := client.Database(config.MongoTable).Collection(config.MongoExecutionCollection).Find(mongoCtx, bson.M{"schedule_id": id})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		executions := []model.Execution{}
+		err = cursor.All(mongoCtx, &executions)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		startedMux.Lock()
+		defer startedMux.Unlock()
+		if len(executions) == 0 || len(executions) != started {
+			t.Error("unexpected execution count", len(executions), started)
+		}
+		for _, execution := range executions {
+			if execution.Status != expectedStatus {
+				t.Error(execution)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSolarSchedule(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	invalid, _ := json.Marshal(model.ScheduleEntry{Solar: &model.SolarTrigger{Event: "noon", Latitude: 52.52, Longitude: 13.405}, ProcessDeploymentId: "d"})
+	t.Run("unknown event", authRequest(config, "user1", "POST", "/schedules", "application/json", invalid, http.StatusBadRequest, nil))
+
+	id := ""
+	trigger := model.SolarTrigger{Event: model.SolarEventCivilDusk, Latitude: 52.52, Longitude: 13.405, Offset: "-15m"}
+	t.Run("create", createScheduleEntry(config, "user1", model.ScheduleEntry{Solar: &trigger, ProcessDeploymentId: "d"}, &id))
+	t.Run("next runs", func(t *testing.T) {
+		runs := []model.PlannedRun{}
+		authRequest(config, "user1", "GET", "/schedules/"+id+"/next?count=2", "", nil, http.StatusOK, &runs)(t)
+		if len(runs) != 2 || runs[1].Time.Sub(runs[0].Time) < 23*time.Hour || runs[1].Time.Sub(runs[0].Time) > 25*time.Hour {
+			t.Error(runs)
+		}
+	})
+	t.Run("get", func(t *testing.T) {
+		entry := model.ScheduleEntryInfo{}
+		authRequest(config, "user1", "GET", "/schedules/"+id, "", nil, http.StatusOK, &entry)(t)
+		if entry.Solar == nil || *entry.Solar != trigger || entry.Cron != "" {
+			t.Error(entry)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/api/util"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/persistence"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/scheduler"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tests/services"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTraceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+	_, ip, err := services.MongoContainer(ctx, wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	apiPort, err := getFreePort()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	traceparents := make(chan string, 100)
+	processApi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer processApi.Close()
+
+	config := testConfig(apiPort, ip)
+	config.ProcessEndpoint = processApi.URL
+	spanExporter := tracetest.NewInMemoryExporter()
+	//spans are immediately visible with a simple span processor
+	tracer := tracing.NewWithSpanProcessor(ctx, wg, sdktrace.NewSimpleSpanProcessor(spanExporter))
+	db, err := persistence.New(ctx, wg, config, nil, tracer)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctrl := scheduler.New(config, db, processapi.New(config, tracer), nil, tracer)
+	err = ctrl.Start(ctx, wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = api.Start(ctx, wg, config, ctrl, util.NewJwt(config), nil, tracer)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	id := ""
+	t.Run("create with incoming trace context", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		_ = json.NewEncoder(buf).Encode(model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "deployment-1"})
+		req, err := http.NewRequest("POST", "http://localhost:"+config.ApiPort+"/schedules", buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Set("traceparent", "00-"+testTraceId+"-00f067aa0ba902b7-01")
+		err = processapi.SetAuthToken(req, "user1")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		result := model.ScheduleEntry{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		id = result.Id
+	})
+
+	t.Run("check api spans", func(t *testing.T) {
+		spans := spanExporter.GetSpans()
+		server := findSpan(spans, "POST /schedules")
+		add := findSpan(spans, "scheduler.add")
+		set := findSpan(spans, "persistence.set")
+		if server == nil || add == nil || set == nil {
+			t.Error("missing spans", spanNames(spans))
+			return
+		}
+		if server.SpanContext.TraceID().String() != testTraceId || add.SpanContext.TraceID().String() != testTraceId {
+			t.Error("trace context not propagated", server.SpanContext.TraceID(), add.SpanContext.TraceID())
+		}
+		if add.Parent.SpanID() != server.SpanContext.SpanID() || set.Parent.SpanID() != add.SpanContext.SpanID() {
+			t.Error("unexpected span hierarchy")
+		}
+		if !hasAttribute(add.Attributes, attribute.String("schedule.id", id)) {
+			t.Error(add.Attributes)
+		}
+	})
+
+	t.Run("check run spans", func(t *testing.T) {
+		var traceparent string
+		select {
+		case traceparent = <-traceparents:
+		case <-time.After(5 * time.Second):
+			t.Error("timeout")
+			return
+		}
+		parts := strings.Split(traceparent, "-")
+		if len(parts) != 4 {
+			t.Error("missing traceparent header", traceparent)
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+		spans := spanExporter.GetSpans()
+		var run *tracetest.SpanStub
+		for i, span := range spans {
+			if span.Name == "scheduler.run" && span.SpanContext.TraceID().String() == parts[1] {
+				run = &spans[i]
+			}
+		}
+		if run == nil {
+			t.Error("missing run span", spanNames(spans))
+			return
+		}
+		if run.Parent.IsValid() || parts[1] == testTraceId {
+			t.Error("run should start a new trace")
+		}
+		if !hasAttribute(run.Attributes, attribute.String("schedule.id", id)) || !hasAttribute(run.Attributes, attribute.String("schedule.process_deployment_id", "deployment-1")) {
+			t.Error(run.Attributes)
+		}
+		var execute *tracetest.SpanStub
+		for i, span := range spans {
+			if span.Name == "processapi.execute" && span.SpanContext.SpanID().String() == parts[2] {
+				execute = &spans[i]
+			}
+		}
+		if execute == nil || execute.Parent.SpanID() != run.SpanContext.SpanID() {
+			t.Error("header does not reference the execute span of the run", traceparent)
+		}
+	})
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i, span := range spans {
+		if span.Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func spanNames(spans tracetest.SpanStubs) (result []string) {
+	for _, span := range spans {
+		result = append(result, span.Name)
+	}
+	return result
+}
+
+func hasAttribute(attributes []attribute.KeyValue, expected attribute.KeyValue) bool {
+	for _, a := range attributes {
+		if a == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/processapi"
+	"log"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestVersionConflict(t *testing.T) {
+	t.Parallel()
+	config, _ := startTestService(t, nil)
+
+	id := ""
+	t.Run("create", createSchedule(config, "* * * ? *", "deployment-1", "user1", &id, nil, nil, nil))
+
+	entry := model.ScheduleEntry{Cron: "* * * ? *", ProcessDeploymentId: "deployment-2"}
+	t.Run("update without If-Match", updateScheduleIfMatch(config, "user1", id, entry, "", http.StatusOK, `"2"`))
+	t.Run("update with current version", updateScheduleIfMatch(config, "user1", id, entry, `"2"`, http.StatusOK, `"3"`))
+	t.Run("update with outdated version", updateScheduleIfMatch(config, "user1", id, entry, `"2"`, http.StatusPreconditionFailed, ""))
+	t.Run("update with wildcard", updateScheduleIfMatch(config, "user1", id, entry, "*", http.StatusOK, `"4"`))
+	t.Run("update with invalid If-Match", updateScheduleIfMatch(config, "user1", id, entry, "4", http.StatusBadRequest, ""))
+	t.Run("update unknown id", updateScheduleIfMatch(config, "user1", "unknown", entry, `"1"`, http.StatusNotFound, ""))
+	t.Run("delete", deleteSchedule(config, "user1", id))
+}
+
+func updateScheduleIfMatch(config configuration.Config, userId string, entryId string, entry model.ScheduleEntry, ifMatch string, expectedCode int, expectedETag string) func(t *testing.T) {
+	return func(t *testing.T) {
+		endpoint := "http://localhost:" + config.ApiPort
+		path := "/schedules/" + url.PathEscape(entryId)
+		method := "PUT"
+		buf := bytes.NewBuffer([]byte{})
+		err := json.NewEncoder(buf).Encode(entry)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		log.Println("HTTP-CALL=", method, endpoint+path)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		err = processapi.SetAuthToken(req, userId)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedCode {
+			t.Error(resp.StatusCode, expectedCode)
+			return
+		}
+		if expectedETag != "" && resp.Header.Get("ETag") != expectedETag {
+			t.Error(resp.Header.Get("ETag"), expectedETag)
+			return
+		}
+	}
+}
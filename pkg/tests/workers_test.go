@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/model"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Parallel()
+	config, processRequests := startTestService(t, func(config configuration.Config) {
+		config.WorkerPoolSize = 2
+		config.WorkerQueueSize = 100
+	})
+
+	ids := []string{}
+	for i := 0; i < 5; i++ {
+		entry := model.ScheduleEntry{}
+		blackoutRequest(config, false, "POST", "/schedules", model.ScheduleEntry{Cron: "* * * * * *", ProcessDeploymentId: "deployment-1"}, http.StatusOK, &entry)(t)
+		ids = append(ids, entry.Id)
+	}
+	time.Sleep(2500 * time.Millisecond)
+	for _, id := range ids {
+		t.Run("delete "+id, blackoutRequest(config, false, "DELETE", "/schedules/"+id, nil, http.StatusOK, nil))
+	}
+	if len(processRequests) < 5 {
+		t.Error("unexpected process starts", len(processRequests))
+	}
+
+	t.Run("metrics", func(t *testing.T) {
+		resp, err := http.Get("http://localhost:" + config.ApiPort + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, metric := range []string{"process_scheduler_execution_workers_busy", `process_scheduler_execution_queue_depth{priority="low"}`, `process_scheduler_execution_queue_wait_seconds_count{priority="normal"}`} {
+			if !strings.Contains(string(body), metric) {
+				t.Error("missing metric", metric)
+			}
+		}
+	})
+}
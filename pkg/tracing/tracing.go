@@ -0,0 +1,145 @@
+/*
+ * Copyright 2020 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/process-scheduler/pkg/configuration"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const ServiceName = "process-scheduler"
+const TracerName = "github.com/SENERGY-Platform/process-scheduler"
+
+const ExporterNone = "none"
+const ExporterStdout = "stdout"
+const ExporterOtlp = "otlp"
+
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
+// Tracing creates the spans of one service instance.
+// All methods may be called on a nil *Tracing, which results in no-op spans.
+type Tracing struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// New creates the tracer provider selected by config.TracingExporter; with ExporterNone (or an empty value) spans are not recorded.
+// The provider is flushed and stopped when ctx is done.
+func New(ctx context.Context, wg *sync.WaitGroup, config configuration.Config) (*Tracing, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	result := &Tracing{}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.TracingExporter {
+	case "", ExporterNone:
+		result.provider = noop.NewTracerProvider()
+		result.tracer = result.provider.Tracer(TracerName)
+		return result, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOtlp:
+		options := []otlptracehttp.Option{}
+		if config.TracingEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.TracingEndpoint), otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing_exporter %q; expect none, stdout or otlp", config.TracingExporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewWithSpanProcessor(ctx, wg, sdktrace.NewBatchSpanProcessor(exporter)), nil
+}
+
+// NewWithSpanProcessor creates a tracer provider that passes the spans to processor;
+// tests use it with a simple span processor and an in memory exporter to inspect the recorded spans.
+// The provider is flushed and stopped when ctx is done.
+func NewWithSpanProcessor(ctx context.Context, wg *sync.WaitGroup, processor sdktrace.SpanProcessor) *Tracing {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	result := &Tracing{}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+	result.provider = provider
+	result.tracer = provider.Tracer(TracerName)
+
+	if ctx != nil {
+		if wg != nil {
+			wg.Add(1)
+		}
+		go func() {
+			<-ctx.Done()
+			timeout, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+			defer cancel()
+			err := provider.Shutdown(timeout)
+			if err != nil {
+				slog.Error("unable to shutdown tracer provider", "error", err)
+			}
+			if wg != nil {
+				wg.Done()
+			}
+		}()
+	}
+	return result
+}
+
+func (this *Tracing) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if this == nil {
+		return noop.NewTracerProvider().Tracer(TracerName).Start(ctx, name, options...)
+	}
+	return this.tracer.Start(ctx, name, options...)
+}
+
+// Propagator returns the propagator used to transport trace context between services (W3C trace context and baggage)
+func Propagator() propagation.TextMapPropagator {
+	return otel.GetTextMapPropagator()
+}
+
+// End sets the span status according to err and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ScheduleAttributes describes a schedule entry in span attributes
+func ScheduleAttributes(id string, user string, processDeploymentId string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("schedule.id", id),
+		attribute.String("schedule.user", user),
+		attribute.String("schedule.process_deployment_id", processDeploymentId),
+	}
+}